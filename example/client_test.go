@@ -0,0 +1,48 @@
+package example
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/supremind/didiyun-client/pkg"
+)
+
+func Example_clientClose() {
+	ctx := context.Background()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	ebs := c.Ebs()
+	if e = c.Close(); e != nil {
+		log.Fatalln(e)
+	}
+
+	_, e = ebs.Create(ctx, "gz", "gz02", "ExampleClientClose", "SSD", 20)
+	fmt.Println(errors.Is(e, pkg.Closed))
+	// Output: true
+}
+
+func Example_fileTokenSource() {
+	f, e := ioutil.TempFile("", "token")
+	if e != nil {
+		log.Fatalln(e)
+	}
+	defer os.Remove(f.Name())
+	if _, e = f.WriteString("my-token\n"); e != nil {
+		log.Fatalln(e)
+	}
+	f.Close()
+
+	ts := pkg.FileTokenSource(f.Name())
+	token, e := ts.Token()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	fmt.Println(token.AccessToken)
+	// Output: my-token
+}
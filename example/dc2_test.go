@@ -0,0 +1,43 @@
+package example
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/supremind/didiyun-client/pkg"
+)
+
+func Example_dc2CreateStopDelete() {
+	ctx := context.Background()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	dc2 := c.Dc2()
+	id, e := dc2.Create(ctx, "gz", "gz02", &pkg.CreateDc2Options{
+		Name:     "ExampleCreateStopDelete_Dc2",
+		Model:    "dc2.hxd1.c2m4",
+		Password: "Passw0rd!",
+	})
+	if e != nil {
+		log.Fatalln(e)
+	}
+
+	if e = dc2.Stop(ctx, id); e != nil {
+		log.Fatalln(e)
+	}
+	info, e := dc2.Get(ctx, id)
+	if e != nil {
+		log.Fatalln(e)
+	}
+	fmt.Println(info.Status)
+
+	if e = dc2.Delete(ctx, id); e != nil {
+		log.Fatalln(e)
+	}
+	fmt.Println("Dc2 created & deleted ok")
+	// Output:
+	// stopped
+	// Dc2 created & deleted ok
+}
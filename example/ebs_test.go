@@ -2,13 +2,15 @@ package example
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/supremind/didiyun-client/pkg"
 )
 
-func ExampleCreateDeleteExpand_Ebs() {
+func Example_ebsCreateDeleteExpand() {
 	ctx := context.Background()
 	c, e := pkg.NewMock()
 	if e != nil {
@@ -30,3 +32,289 @@ func ExampleCreateDeleteExpand_Ebs() {
 	// Ebs created & deleted ok
 	// Ebs expanded ok
 }
+
+func Example_ebsSnapshot() {
+	ctx := context.Background()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	ebs := c.Ebs()
+	id, e := ebs.Create(ctx, "gz", "gz02", "ExampleSnapshot_Ebs", "SSD", 20)
+	if e != nil {
+		log.Fatalln(e)
+	}
+
+	snap, e := ebs.CreateSnapshot(ctx, id, "ExampleSnapshot_Ebs")
+	if e != nil {
+		log.Fatalln(e)
+	}
+	restored, e := ebs.CreateFromSnapshot(ctx, "gz", "gz02", "ExampleSnapshot_Ebs_restored", snap, "SSD", 20)
+	if e != nil {
+		log.Fatalln(e)
+	}
+
+	for _, e := range []error{ebs.DeleteSnapshot(ctx, snap), ebs.Delete(ctx, restored), ebs.Delete(ctx, id)} {
+		if e != nil {
+			log.Fatalln(e)
+		}
+	}
+	fmt.Println("Ebs snapshot created & restored ok")
+	// Output: Ebs snapshot created & restored ok
+}
+
+func Example_ebsCreateOrGet() {
+	ctx := context.Background()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	ebs := c.Ebs()
+	id, e := ebs.CreateOrGet(ctx, "gz", "gz02", "ExampleCreateOrGet_Ebs", "SSD", 20, "token-1")
+	if e != nil {
+		log.Fatalln(e)
+	}
+	again, e := ebs.CreateOrGet(ctx, "gz", "gz02", "ExampleCreateOrGet_Ebs", "SSD", 20, "token-1")
+	if e != nil {
+		log.Fatalln(e)
+	}
+	fmt.Println("same ebs:", id == again)
+
+	_, e = ebs.CreateOrGet(ctx, "gz", "gz02", "ExampleCreateOrGet_Ebs", "SSD", 40, "token-1")
+	fmt.Println("conflict:", errors.Is(e, pkg.AlreadyExists))
+
+	if e = ebs.Delete(ctx, id); e != nil {
+		log.Fatalln(e)
+	}
+	// Output:
+	// same ebs: true
+	// conflict: true
+}
+
+func Example_ebsList() {
+	ctx := context.Background()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	ebs := c.Ebs()
+	for _, name := range []string{"ExampleList_Ebs_a", "ExampleList_Ebs_b", "Other_Ebs"} {
+		if _, e := ebs.Create(ctx, "gz", "gz02", name, "SSD", 20); e != nil {
+			log.Fatalln(e)
+		}
+	}
+
+	list, e := ebs.List(ctx, &pkg.EbsFilter{RegionID: "gz", ZoneID: "gz02", NamePrefix: "ExampleList_", NotAttached: true})
+	if e != nil {
+		log.Fatalln(e)
+	}
+	fmt.Println("unattached:", len(list))
+	// Output: unattached: 2
+}
+
+func Example_ebsAttachTo() {
+	ctx := context.Background()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	ebs := c.Ebs()
+	if _, e = c.Dc2().Create(ctx, "gz", "gz02", &pkg.CreateDc2Options{Name: "node-1"}); e != nil {
+		log.Fatalln(e)
+	}
+	id, e := ebs.Create(ctx, "gz", "gz02", "ExampleAttachTo_Ebs", "SSD", 20)
+	if e != nil {
+		log.Fatalln(e)
+	}
+
+	device, e := ebs.AttachTo(ctx, id, pkg.Dc2Ref{Name: "node-1"})
+	if e != nil {
+		log.Fatalln(e)
+	}
+	fmt.Println("attached as", device)
+	// Output: attached as mock-device
+}
+
+func Example_ebsDetachFrom() {
+	ctx := context.Background()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	ebs := c.Ebs()
+	node, e := c.Dc2().Create(ctx, "gz", "gz02", &pkg.CreateDc2Options{Name: "node-1"})
+	if e != nil {
+		log.Fatalln(e)
+	}
+	info, e := c.Dc2().Get(ctx, node)
+	if e != nil {
+		log.Fatalln(e)
+	}
+	if _, e = c.Dc2().Create(ctx, "gz", "gz02", &pkg.CreateDc2Options{Name: "node-2"}); e != nil {
+		log.Fatalln(e)
+	}
+	id, e := ebs.Create(ctx, "gz", "gz02", "ExampleDetachFrom_Ebs", "SSD", 20)
+	if e != nil {
+		log.Fatalln(e)
+	}
+	if _, e = ebs.AttachTo(ctx, id, pkg.Dc2Ref{Ip: info.GetIp()}); e != nil { // detached by name later
+		log.Fatalln(e)
+	}
+
+	e = ebs.DetachFrom(ctx, id, pkg.Dc2Ref{Name: "node-2"})
+	fmt.Println("conflict:", errors.Is(e, pkg.Conflict))
+
+	for i := 0; i < 2; i++ { // detaching again is ok
+		if e = ebs.DetachFrom(ctx, id, pkg.Dc2Ref{Name: "node-1"}); e != nil {
+			log.Fatalln(e)
+		}
+	}
+	fmt.Println("Ebs detached ok")
+	// Output:
+	// conflict: true
+	// Ebs detached ok
+}
+
+func Example_ebsNotFound() {
+	ctx := context.Background()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	ebs := c.Ebs()
+	_, e = ebs.Get(ctx, "no-such-ebs")
+	fmt.Println("get:", errors.Is(e, pkg.NotFound))
+	e = ebs.Expand(ctx, "no-such-ebs", 40)
+	fmt.Println("expand:", errors.Is(e, pkg.NotFound))
+	e = ebs.Detach(ctx, "no-such-ebs")
+	fmt.Println("detach:", errors.Is(e, pkg.NotFound))
+	// Output:
+	// get: true
+	// expand: true
+	// detach: true
+}
+
+func Example_ebsInvalidSize() {
+	ctx := context.Background()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	ebs := c.Ebs()
+	_, e = ebs.Create(ctx, "gz", "gz02", "ExampleInvalidSize_Ebs", "ssd", 20)
+	fmt.Println("unknown type:", errors.Is(e, pkg.InvalidArgument))
+
+	id, e := ebs.Create(ctx, "gz", "gz02", "ExampleInvalidSize_Ebs", pkg.EbsTypeSSD, 20)
+	if e != nil {
+		log.Fatalln(e)
+	}
+	e = ebs.Expand(ctx, id, 25)
+	fmt.Println("invalid step:", errors.Is(e, pkg.InvalidArgument))
+	// Output:
+	// unknown type: true
+	// invalid step: true
+}
+
+func Example_ebsCreateWithOptions() {
+	ctx := context.Background()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	ebs := c.Ebs()
+	if _, e = c.Dc2().Create(ctx, "gz", "gz02", &pkg.CreateDc2Options{Name: "node-1"}); e != nil {
+		log.Fatalln(e)
+	}
+	id, e := ebs.CreateWithOptions(ctx, "gz", "gz02", &pkg.CreateEbsOptions{
+		Name:         "ExampleCreateWithOptions_Ebs",
+		Type:         pkg.EbsTypeSSD,
+		SizeGB:       40,
+		PayPeriod:    12,
+		AutoContinue: true,
+		Tags:         []string{"team:storage"},
+		Dc2:          &pkg.Dc2Ref{Name: "node-1"},
+	})
+	if e != nil {
+		log.Fatalln(e)
+	}
+	info, e := ebs.Get(ctx, id)
+	if e != nil {
+		log.Fatalln(e)
+	}
+	fmt.Println(info.GetEbsTags(), info.GetDc2().GetName())
+	// Output: [team:storage] node-1
+}
+
+func Example_ebsBatch() {
+	ctx := context.Background()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	ebs := c.Ebs()
+	if _, e = c.Dc2().Create(ctx, "gz", "gz02", &pkg.CreateDc2Options{Name: "node-1"}); e != nil {
+		log.Fatalln(e)
+	}
+	attachments := make(map[string]pkg.Dc2Ref)
+	for _, name := range []string{"ExampleBatch_Ebs_a", "ExampleBatch_Ebs_b"} {
+		id, e := ebs.Create(ctx, "gz", "gz02", name, pkg.EbsTypeSSD, 20)
+		if e != nil {
+			log.Fatalln(e)
+		}
+		attachments[id] = pkg.Dc2Ref{Name: "node-1"}
+	}
+
+	attached, e := ebs.AttachMany(ctx, attachments)
+	if e != nil {
+		log.Fatalln(e)
+	}
+	var ids []string
+	for id, r := range attached {
+		if r.Err != nil {
+			log.Fatalln(r.Err)
+		}
+		ids = append(ids, id)
+	}
+
+	detached, e := ebs.DetachMany(ctx, append(ids, "no-such-ebs"))
+	if e != nil {
+		log.Fatalln(e)
+	}
+	fmt.Println("detached:", detached[ids[0]] == nil && detached[ids[1]] == nil)
+	fmt.Println("not found:", errors.Is(detached["no-such-ebs"], pkg.NotFound))
+	// Output:
+	// detached: true
+	// not found: true
+}
+
+func Example_ebsWaitFor() {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	ebs := c.Ebs()
+	dc2UUID, e := c.Dc2().Create(ctx, "gz", "gz02", &pkg.CreateDc2Options{Name: "node-1"})
+	if e != nil {
+		log.Fatalln(e)
+	}
+	id, e := ebs.Create(ctx, "gz", "gz02", "ExampleWaitFor_Ebs", pkg.EbsTypeSSD, 20)
+	if e != nil {
+		log.Fatalln(e)
+	}
+	if _, e = ebs.AttachTo(ctx, id, pkg.Dc2Ref{Name: "node-1"}); e != nil {
+		log.Fatalln(e)
+	}
+
+	if _, e = ebs.WaitFor(ctx, id, pkg.EbsAttachedTo(dc2UUID)); e != nil {
+		log.Fatalln(e)
+	}
+	fmt.Println("Ebs attached")
+
+	_, e = ebs.WaitFor(ctx, id, pkg.EbsDetached) // never detached
+	fmt.Println("timeout:", errors.Is(e, context.DeadlineExceeded))
+	// Output:
+	// Ebs attached
+	// timeout: true
+}
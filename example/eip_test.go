@@ -0,0 +1,35 @@
+package example
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/supremind/didiyun-client/pkg"
+)
+
+func Example_eipBindUnbind() {
+	ctx := context.Background()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	eip := c.Eip()
+	id, e := eip.Create(ctx, "gz", "gz02", 2, true, nil)
+	if e != nil {
+		log.Fatalln(e)
+	}
+
+	if e = eip.Bind(ctx, id, pkg.EipBindingSlb, "ExampleBindUnbind_Eip"); e != nil {
+		log.Fatalln(e)
+	}
+	if e = eip.Unbind(ctx, id); e != nil {
+		log.Fatalln(e)
+	}
+
+	if e = eip.Delete(ctx, id); e != nil {
+		log.Fatalln(e)
+	}
+	fmt.Println("Eip bound & unbound ok")
+	// Output: Eip bound & unbound ok
+}
@@ -2,13 +2,15 @@ package example
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/supremind/didiyun-client/pkg"
 )
 
-func ExampleCreateDelete_Slb() {
+func Example_slbCreateDelete() {
 	ctx := context.Background()
 	c, e := pkg.NewMock()
 	if e != nil {
@@ -32,7 +34,7 @@ func ExampleCreateDelete_Slb() {
 	// Output: Slb created & deleted ok
 }
 
-func ExampleSyncListeners_Slb() {
+func Example_slbSyncListeners() {
 	ctx := context.Background()
 	c, e := pkg.NewMock()
 	if e != nil {
@@ -50,6 +52,11 @@ func ExampleSyncListeners_Slb() {
 		}
 	}()
 
+	for _, name := range []string{"atom9", "atom8"} {
+		if _, e := c.Dc2().Create(ctx, "gz", "gz02", &pkg.CreateDc2Options{Name: name}); e != nil {
+			log.Fatalln(e)
+		}
+	}
 	listeners := []*pkg.Listener{
 		{Name: "http", SlbPort: 5090, Dc2Port: 5092, Protocol: "TCP"},
 		{Name: "rtmp", SlbPort: 5080, Dc2Port: 5082, Protocol: "TCP"},
@@ -62,7 +69,7 @@ func ExampleSyncListeners_Slb() {
 	// Output: Slb listeners synced ok
 }
 
-func ExampleSyncListenerMembers_Slb() {
+func Example_slbSyncListenerMembers() {
 	ctx := context.Background()
 	c, e := pkg.NewMock()
 	if e != nil {
@@ -80,10 +87,37 @@ func ExampleSyncListenerMembers_Slb() {
 		}
 	}()
 
-	if e := slb.SyncListenerMembers(ctx, id, []string{"atom7", "atom8"}); e != nil {
+	if _, e := c.Dc2().Create(ctx, "gz", "gz02", &pkg.CreateDc2Options{Name: "atom7"}); e != nil {
+		log.Fatalln(e)
+	}
+	// atom7 is synced, members of atom8 not found are kept
+	e = slb.SyncListenerMembers(ctx, id, []string{"atom7", "atom8"})
+	var missing *pkg.Dc2NotFoundError
+	if !errors.As(e, &missing) || !errors.Is(e, pkg.NotFound) {
 		log.Fatalln(e)
 	}
 
-	fmt.Println("Slb listener members synced ok")
-	// Output: Slb listener members synced ok
+	fmt.Println("Slb listener members synced, missing", missing.Names)
+	// Output: Slb listener members synced, missing [atom8]
+}
+
+func Example_slbWaitFor() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	slb := c.Slb(vpcUuid)
+	id, e := slb.Create(ctx, "gz", "gz02", "ExampleWaitFor_Slb", 2)
+	if e != nil {
+		log.Fatalln(e)
+	}
+
+	info, e := slb.WaitFor(ctx, id, pkg.SlbExternalIPAssigned)
+	if e != nil {
+		log.Fatalln(e)
+	}
+	fmt.Println("Slb got external ip", info.GetBeip().GetIp() != "")
+	// Output: Slb got external ip true
 }
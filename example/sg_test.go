@@ -0,0 +1,38 @@
+package example
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/supremind/didiyun-client/pkg"
+)
+
+func Example_sgSyncRules() {
+	ctx := context.Background()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	sg := c.Sg()
+	id, e := sg.Create(ctx, "gz", vpcUuid, "ExampleSyncRules_Sg", nil)
+	if e != nil {
+		log.Fatalln(e)
+	}
+	defer func() {
+		if e = sg.Delete(ctx, id); e != nil {
+			log.Fatalln(e)
+		}
+	}()
+
+	rules := []pkg.SgRule{
+		{Type: pkg.SgRuleIngress, Protocol: "TCP", StartPort: 22, EndPort: 22, AllowedCidr: "0.0.0.0/0"},
+		{Type: pkg.SgRuleIngress, Protocol: "TCP", StartPort: 30000, EndPort: 32767, AllowedCidr: "10.0.0.0/8"},
+	}
+	if e := sg.SyncRules(ctx, id, rules); e != nil {
+		log.Fatalln(e)
+	}
+
+	fmt.Println("Sg rules synced ok")
+	// Output: Sg rules synced ok
+}
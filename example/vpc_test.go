@@ -0,0 +1,39 @@
+package example
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/supremind/didiyun-client/pkg"
+)
+
+func Example_vpcCreateGetByName() {
+	ctx := context.Background()
+	c, e := pkg.NewMock()
+	if e != nil {
+		log.Fatalln(e)
+	}
+	vpc := c.Vpc()
+	id, e := vpc.Create(ctx, "gz", "ExampleCreateGetByName_Vpc", "172.16.0.0/16", []*pkg.Subnet{
+		{Name: "gz02", Cidr: "172.16.0.0/20", ZoneID: "gz02"},
+	})
+	if e != nil {
+		log.Fatalln(e)
+	}
+
+	info, e := vpc.GetByName(ctx, "gz", "ExampleCreateGetByName_Vpc")
+	if e != nil {
+		log.Fatalln(e)
+	}
+	fmt.Println(info.VpcUuid == id)
+
+	subnets, e := vpc.ListSubnets(ctx, "gz", id)
+	if e != nil {
+		log.Fatalln(e)
+	}
+	fmt.Println(len(subnets))
+	// Output:
+	// true
+	// 1
+}
@@ -6,6 +6,7 @@ require (
 	github.com/didiyun/didiyun-go-sdk v0.0.0-20200702070057-217ddce30166
 	github.com/pborman/uuid v1.2.0
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
+	golang.org/x/time v0.0.0-20191024005414-555d28b269f0
 	google.golang.org/grpc v1.29.0-dev.0.20200402235506-fe1d8e71817f
 	k8s.io/klog v1.0.0
 )
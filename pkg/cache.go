@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"k8s.io/klog"
+)
+
+const dc2CacheRefreshTimeout = 30 * time.Second
+
+// dc2Cache caches name and ip to uuid mappings of dc2, listed by vpc
+type dc2Cache struct {
+	ttl  time.Duration
+	list func(ctx context.Context, cond *compute.ListDc2Condition, visit func(*compute.Dc2Info) bool) error
+
+	mu         sync.Mutex
+	gen        int                     // bumped by invalidate, refreshes started before are not cached
+	snapshots  map[string]*dc2Snapshot // by vpc uuid, "" for all vpcs
+	refreshing map[string]*dc2Refresh  // by vpc uuid, shared by concurrent lookups
+}
+
+// dc2Snapshot is immutable once listed
+type dc2Snapshot struct {
+	fetched time.Time
+	byName  map[string]string
+	byIp    map[string]string
+}
+
+// dc2Refresh is a listing in progress, snap and err are set once done is closed
+type dc2Refresh struct {
+	started time.Time
+	done    chan struct{}
+	snap    *dc2Snapshot
+	err     error
+}
+
+func newDc2Cache(ttl time.Duration, list func(ctx context.Context, cond *compute.ListDc2Condition, visit func(*compute.Dc2Info) bool) error) *dc2Cache {
+	return &dc2Cache{
+		ttl:        ttl,
+		list:       list,
+		snapshots:  make(map[string]*dc2Snapshot),
+		refreshing: make(map[string]*dc2Refresh),
+	}
+}
+
+// get returns the snapshot of vpc, it is refreshed if expired or older than notBefore,
+// a stale snapshot is returned if refreshing fails.
+// Lookups of a vpc share one refresh in progress, which does not block lookups of other vpcs
+func (t *dc2Cache) get(ctx context.Context, vpcUuid string, notBefore time.Time) (*dc2Snapshot, error) {
+	t.mu.Lock()
+	s := t.snapshots[vpcUuid]
+	if s != nil && time.Since(s.fetched) < t.ttl && !s.fetched.Before(notBefore) {
+		t.mu.Unlock()
+		return s, nil
+	}
+
+	r := t.refreshing[vpcUuid]
+	if r == nil || r.started.Before(notBefore) {
+		r = &dc2Refresh{started: time.Now(), done: make(chan struct{})}
+		t.refreshing[vpcUuid] = r
+		go t.refresh(vpcUuid, r, t.gen)
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("wait for dc2 cache of vpc %q error %w", vpcUuid, ctx.Err())
+	case <-r.done:
+	}
+
+	if r.err != nil {
+		if s != nil {
+			klog.Warningf("refresh dc2 cache of vpc %q error %v, use the stale one fetched at %s", vpcUuid, r.err, s.fetched)
+			return s, nil
+		}
+		return nil, r.err
+	}
+	return r.snap, nil
+}
+
+// refresh lists dc2 of the vpc into r, and caches it unless invalidated or a newer one is cached meanwhile.
+// It is not bound to the context of any lookup, which may end while others still wait for it
+func (t *dc2Cache) refresh(vpcUuid string, r *dc2Refresh, gen int) {
+	defer close(r.done)
+	ctx, cancel := context.WithTimeout(context.Background(), dc2CacheRefreshTimeout)
+	defer cancel()
+
+	klog.V(4).Infof("refreshing dc2 cache of vpc %q", vpcUuid)
+	var cond *compute.ListDc2Condition
+	if vpcUuid != "" {
+		cond = &compute.ListDc2Condition{VpcUuids: []string{vpcUuid}}
+	}
+	fresh := &dc2Snapshot{
+		fetched: r.started,
+		byName:  make(map[string]string),
+		byIp:    make(map[string]string),
+	}
+	e := t.list(ctx, cond, func(d *compute.Dc2Info) bool {
+		fresh.byName[d.GetName()] = d.GetDc2Uuid()
+		fresh.byIp[d.GetIp()] = d.GetDc2Uuid()
+		return true
+	})
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.refreshing[vpcUuid] == r {
+		delete(t.refreshing, vpcUuid)
+	}
+	if e != nil {
+		r.err = e
+		return
+	}
+	r.snap = fresh
+	if cur := t.snapshots[vpcUuid]; t.gen == gen && (cur == nil || cur.fetched.Before(fresh.fetched)) {
+		t.snapshots[vpcUuid] = fresh
+	}
+}
+
+func (t *dc2Cache) invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.gen++
+	t.snapshots = make(map[string]*dc2Snapshot)
+	t.refreshing = make(map[string]*dc2Refresh)
+}
+
+// resolve returns uuids in the order of names, and names not found
+func (t *dc2Snapshot) resolve(names []string) ([]string, []string) {
+	var dc2Uuids, missing []string
+	for _, n := range names {
+		if id, ok := t.byName[n]; ok {
+			dc2Uuids = append(dc2Uuids, id)
+		} else {
+			missing = append(missing, n)
+		}
+	}
+	return dc2Uuids, missing
+}
@@ -0,0 +1,162 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+)
+
+// fakeDc2List lists dc2s of every vpc, blocked by the gate of the vpc if any
+type fakeDc2List struct {
+	dc2s  []*compute.Dc2Info
+	err   error
+	gates map[string]chan struct{}
+	calls int32
+}
+
+func (t *fakeDc2List) list(ctx context.Context, cond *compute.ListDc2Condition, visit func(*compute.Dc2Info) bool) error {
+	atomic.AddInt32(&t.calls, 1)
+	vpc := ""
+	if cond != nil {
+		vpc = cond.VpcUuids[0]
+	}
+	if g, ok := t.gates[vpc]; ok {
+		<-g
+	}
+	if t.err != nil {
+		return t.err
+	}
+	for _, d := range t.dc2s {
+		if !visit(d) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestDc2CacheSingleFlight(t *testing.T) {
+	fake := &fakeDc2List{dc2s: fakeDc2s(3), gates: map[string]chan struct{}{"": make(chan struct{})}}
+	c := newDc2Cache(time.Minute, fake.list)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s, e := c.get(ctx, "", time.Time{}); e != nil || s.byName["node-1"] != "uuid-1" {
+				t.Errorf("got %v, error %v", s, e)
+			}
+		}()
+	}
+	for atomic.LoadInt32(&fake.calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// other vpcs are not blocked by the refresh in progress
+	if s, e := c.get(ctx, "vpc-1", time.Time{}); e != nil || s.byIp["10.0.0.2"] != "uuid-2" {
+		t.Errorf("got %v, error %v", s, e)
+	}
+
+	close(fake.gates[""])
+	wg.Wait()
+	if n := atomic.LoadInt32(&fake.calls); n != 2 {
+		t.Errorf("expect 2 listings, got %d", n)
+	}
+}
+
+func TestDc2CacheRefresh(t *testing.T) {
+	fake := &fakeDc2List{dc2s: fakeDc2s(1)}
+	c := newDc2Cache(time.Minute, fake.list)
+	ctx := context.Background()
+
+	first, e := c.get(ctx, "", time.Time{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if s, _ := c.get(ctx, "", time.Time{}); s != first || fake.calls != 1 {
+		t.Errorf("expect the cached snapshot, got %d listings", fake.calls)
+	}
+
+	// refreshed if older than notBefore
+	fake.dc2s = fakeDc2s(2)
+	s, e := c.get(ctx, "", time.Now())
+	if e != nil {
+		t.Fatal(e)
+	}
+	if s == first || s.byName["node-1"] != "uuid-1" || fake.calls != 2 {
+		t.Errorf("expect a refreshed snapshot, got %d listings", fake.calls)
+	}
+
+	// stale one is used if refreshing fails
+	fake.err = errors.New("list error")
+	stale, e := c.get(ctx, "", time.Now())
+	if e != nil || stale != s {
+		t.Errorf("expect the stale snapshot, got error %v", e)
+	}
+
+	// no stale one to fall back to once invalidated
+	c.invalidate()
+	if _, e := c.get(ctx, "", time.Time{}); e != fake.err {
+		t.Errorf("expect the list error, got %v", e)
+	}
+}
+
+func TestDc2CacheExpire(t *testing.T) {
+	fake := &fakeDc2List{dc2s: fakeDc2s(1)}
+	c := newDc2Cache(10*time.Millisecond, fake.list)
+	ctx := context.Background()
+
+	if _, e := c.get(ctx, "", time.Time{}); e != nil {
+		t.Fatal(e)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, e := c.get(ctx, "", time.Time{}); e != nil {
+		t.Fatal(e)
+	}
+	if fake.calls != 2 {
+		t.Errorf("expect expired snapshot refreshed, got %d listings", fake.calls)
+	}
+}
+
+func TestDc2CacheCanceledLookup(t *testing.T) {
+	fake := &fakeDc2List{dc2s: fakeDc2s(1), gates: map[string]chan struct{}{"": make(chan struct{})}}
+	c := newDc2Cache(time.Minute, fake.list)
+
+	// the lookup starting the refresh gives up
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error, 1)
+	go func() {
+		_, e := c.get(ctx, "", time.Time{})
+		canceled <- e
+	}()
+	for atomic.LoadInt32(&fake.calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	waiting := make(chan error, 1)
+	go func() {
+		s, e := c.get(context.Background(), "", time.Time{})
+		if e == nil && s.byName["node-0"] != "uuid-0" {
+			e = errors.New("node-0 not cached")
+		}
+		waiting <- e
+	}()
+	cancel()
+	if e := <-canceled; !errors.Is(e, context.Canceled) {
+		t.Errorf("expect canceled, got %v", e)
+	}
+
+	// others still get the refreshed one
+	close(fake.gates[""])
+	if e := <-waiting; e != nil {
+		t.Error(e)
+	}
+	if n := atomic.LoadInt32(&fake.calls); n != 1 {
+		t.Errorf("expect 1 listing, got %d", n)
+	}
+}
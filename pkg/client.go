@@ -3,8 +3,11 @@ package pkg
 import (
 	"context"
 	"crypto/tls"
-	"errors"
+	"crypto/x509"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
 	"time"
 
 	"github.com/didiyun/didiyun-go-sdk/base/v1"
@@ -17,65 +20,174 @@ import (
 )
 
 const (
-	endpoint     = "open.didiyunapi.com:8080"
-	pollInterval = 3 * time.Second
-	maxDc2       = 500
-	maxSlb       = 1000
-
-	ebsNotFoundMsg  = "找不到指定EBS"
-	slbNotFoundMsg  = "找不到指定SLB"
-	slbNotFoundCode = 41070 // 查询SLB信息失败
-)
+	defaultEndpoint = "open.didiyunapi.com:8080"
+	maxDc2          = 500
+	maxSlb          = 1000
 
-var (
-	NotFound = errors.New("not found")
+	// dc2CacheMinRefresh is the least interval of refreshing cached dc2 for names not found
+	dc2CacheMinRefresh = 10 * time.Second
 )
 
 type Client interface {
+	io.Closer
 	Ebs() EbsClient
 	Slb(vpcUuid string) SlbClient
+	Dc2() Dc2Client
+	Eip() EipClient
+	Vpc() VpcClient
+	Sg() SgClient
+	// InvalidateDc2Cache drops cached dc2 lookups, eg. after instances are replaced
+	InvalidateDc2Cache()
 }
 
 type Config struct {
 	Token   string
 	Timeout time.Duration
+
+	// TokenSource provides the api token for every request, Token is ignored if set,
+	// see FileTokenSource and EnvTokenSource for tokens rotated outside of the process
+	TokenSource oauth2.TokenSource
+
+	// Endpoint overrides the default didiyun api endpoint, eg. a local fake api server or a proxy
+	Endpoint string
+	// CAFile is a PEM encoded CA bundle to verify the endpoint, system roots are used if empty
+	CAFile string
+	// InsecureSkipVerify disables verification of the endpoint certificate
+	InsecureSkipVerify bool
+	// Insecure dials the endpoint without TLS, only for testing against a plaintext server
+	Insecure bool
+	// UserAgent is prepended to the grpc user agent
+	UserAgent string
+	// DialOptions are appended to the options built from this config
+	DialOptions []grpc.DialOption
+
+	// JobPoll controls polling of async jobs, eg. creating or attaching an ebs, and of WaitFor ebs or slb states
+	JobPoll PollPolicy
+	// Retry controls retrying of requests failed by transient errors
+	Retry RetryPolicy
+	// RateLimit throttles all requests of the client, including retries and job polls
+	RateLimit RateLimit
+	// MethodRateLimits throttles requests of single grpc methods, keyed by method names, eg. "ListDc2"
+	MethodRateLimits map[string]RateLimit
+
+	// Dc2CacheTTL enables caching of dc2 name and ip to uuid lookups, 0 disables the cache
+	Dc2CacheTTL time.Duration
 }
 
 type client struct {
-	conn *grpc.ClientConn
+	conn   *grpc.ClientConn
+	closed int32
+	poll   PollPolicy
 
 	// for helper
-	job compute.CommonClient
-	dc2 compute.Dc2Client
+	jobs  *jobWatcher
+	dc2   compute.Dc2Client
+	cache *dc2Cache // nil if disabled
 }
 
 func New(cfg *Config) (Client, error) {
-	cred := oauth.NewOauthAccess(&oauth2.Token{
-		AccessToken: cfg.Token,
-		TokenType:   "bearer",
-	})
-	conn, e := grpc.Dial(endpoint,
-		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})),
-		grpc.WithPerRPCCredentials(cred),
-		grpc.WithTimeout(cfg.Timeout),
-	)
+	opts, e := dialOptions(cfg)
 	if e != nil {
 		return nil, e
 	}
-	return &client{
-		conn: conn,
-		job:  compute.NewCommonClient(conn),
-		dc2:  compute.NewDc2Client(conn),
-	}, nil
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+
+	cli := &client{poll: cfg.JobPoll.withDefaults()}
+	retrier := &retrier{policy: cfg.Retry.withDefaults()}
+	limiter := newRateLimiter(cfg.RateLimit, cfg.MethodRateLimits)
+	opts = append(opts, grpc.WithChainUnaryInterceptor(cli.closedInterceptor, retrier.intercept, limiter.intercept))
+	conn, e := grpc.Dial(endpoint, opts...)
+	if e != nil {
+		return nil, e
+	}
+	cli.conn = conn
+	cli.jobs = newJobWatcher(compute.NewCommonClient(conn))
+	cli.dc2 = compute.NewDc2Client(conn)
+	if cfg.Dc2CacheTTL > 0 {
+		cli.cache = newDc2Cache(cfg.Dc2CacheTTL, cli.listDc2)
+	}
+	return cli, nil
 }
 
+func dialOptions(cfg *Config) ([]grpc.DialOption, error) {
+	ts := cfg.TokenSource
+	if ts == nil {
+		ts = oauth2.StaticTokenSource(bearerToken(cfg.Token))
+	}
+	var cred credentials.PerRPCCredentials = oauth.TokenSource{TokenSource: ts}
+	opts := []grpc.DialOption{grpc.WithTimeout(cfg.Timeout)}
+
+	if cfg.Insecure {
+		opts = append(opts, grpc.WithInsecure())
+		cred = plaintextCredentials{ts}
+	} else {
+		tlsCfg := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+		if cfg.CAFile != "" {
+			pem, e := ioutil.ReadFile(cfg.CAFile)
+			if e != nil {
+				return nil, fmt.Errorf("read ca file error %w", e)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificate found in ca file %s", cfg.CAFile)
+			}
+			tlsCfg.RootCAs = pool
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	}
+	opts = append(opts, grpc.WithPerRPCCredentials(cred))
+
+	if cfg.UserAgent != "" {
+		opts = append(opts, grpc.WithUserAgent(cfg.UserAgent))
+	}
+	return append(opts, cfg.DialOptions...), nil
+}
+
+// plaintextCredentials sends the token over a plaintext connection, which oauth credentials refuse to
+type plaintextCredentials struct {
+	oauth2.TokenSource
+}
+
+func (t plaintextCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, e := t.Token()
+	if e != nil {
+		return nil, e
+	}
+	return map[string]string{"authorization": token.Type() + " " + token.AccessToken}, nil
+}
+
+func (plaintextCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// Close closes the underlying connection, all sub clients fail with Closed afterwards
 func (t *client) Close() error {
+	if !atomic.CompareAndSwapInt32(&t.closed, 0, 1) {
+		return nil
+	}
 	return t.conn.Close()
 }
 
+func (t *client) closedInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if atomic.LoadInt32(&t.closed) != 0 {
+		return Closed
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (t *client) InvalidateDc2Cache() {
+	if t.cache != nil {
+		t.cache.invalidate()
+	}
+}
+
 func (t *client) Ebs() EbsClient {
 	return &ebsClient{
 		cli:    compute.NewEbsClient(t.conn),
+		snap:   compute.NewSnapClient(t.conn),
 		helper: t,
 	}
 }
@@ -88,113 +200,209 @@ func (t *client) Slb(vpcUuid string) SlbClient {
 	}
 }
 
+func (t *client) Dc2() Dc2Client {
+	return &dc2Client{
+		cli:    t.dc2,
+		helper: t,
+	}
+}
+
+func (t *client) Eip() EipClient {
+	return &eipClient{
+		cli:    compute.NewEipClient(t.conn),
+		helper: t,
+	}
+}
+
+func (t *client) Vpc() VpcClient {
+	return &vpcClient{
+		cli:    compute.NewVpcClient(t.conn),
+		helper: t,
+	}
+}
+
+func (t *client) Sg() SgClient {
+	return &sgClient{
+		cli:    compute.NewSgClient(t.conn),
+		helper: t,
+	}
+}
+
 type helper interface {
+	InvalidateDc2Cache()
 	getDc2UUIDByName(ctx context.Context, name string) (string, error)
-	getDc2UUIDsByNames(ctx context.Context, vpcUuid string, names []string) ([]string, error)
+	getDc2UUIDsByNames(ctx context.Context, vpcUuid string, names []string) ([]string, []string, error)
 	getDc2UUIDByIp(ctx context.Context, ip string) (string, error)
+	getDc2UUID(ctx context.Context, ref Dc2Ref) (string, error)
 	waitForJob(ctx context.Context, info *base.JobInfo, regionID, zoneID string) (*base.JobInfo, error)
+	pollUntil(ctx context.Context, what string, check func(context.Context) (bool, error)) error
 }
 
 func (t *client) getDc2UUIDByName(ctx context.Context, name string) (string, error) {
 	klog.V(4).Infof("getting dc2 uuid by %s", name)
-	req := &compute.ListDc2Request{
-		Start:     0,
-		Limit:     maxDc2,
-		Simplify:  true,
-		Condition: &compute.ListDc2Condition{Dc2Name: name},
-	}
-	resp, e := t.dc2.ListDc2(ctx, req)
-	if e != nil {
-		return "", fmt.Errorf("get dc2 error %w", e)
-	}
-	if resp.Error.Errno != 0 {
-		return "", fmt.Errorf("get dc2 error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+	if t.cache != nil {
+		snap, e := t.cache.get(ctx, "", time.Time{})
+		if e != nil {
+			return "", e
+		}
+		if id, ok := snap.byName[name]; ok {
+			return id, nil
+		}
 	}
-	for _, d := range resp.Data {
+
+	var uuid string
+	e := t.listDc2(ctx, &compute.ListDc2Condition{Dc2Name: name}, func(d *compute.Dc2Info) bool {
 		if d.GetName() == name {
-			return d.GetDc2Uuid(), nil
+			uuid = d.GetDc2Uuid()
+			return false
 		}
+		return true
+	})
+	if e != nil {
+		return "", e
+	}
+	if uuid == "" {
+		return "", fmt.Errorf("dc2 %s %w", name, NotFound)
 	}
-	return "", fmt.Errorf("dc2 %s is not found", name)
+	return uuid, nil
 }
 
-func (t *client) getDc2UUIDsByNames(ctx context.Context, vpcUuid string, names []string) ([]string, error) {
+// getDc2UUIDsByNames resolves names in the vpc, returns uuids in the order of names and names not found
+func (t *client) getDc2UUIDsByNames(ctx context.Context, vpcUuid string, names []string) ([]string, []string, error) {
 	if len(names) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	klog.V(4).Infof("getting dc2 uuids by names")
-	req := &compute.ListDc2Request{
-		Start:    0,
-		Limit:    maxDc2,
-		Simplify: true,
+	if t.cache != nil {
+		snap, e := t.cache.get(ctx, vpcUuid, time.Time{})
+		if e != nil {
+			return nil, nil, e
+		}
+		dc2Uuids, missing := snap.resolve(names)
+		// may be created after cached, refresh once, but not again and again for names never resolved
+		if notBefore := time.Now().Add(-dc2CacheMinRefresh); len(missing) > 0 && snap.fetched.Before(notBefore) {
+			if snap, e = t.cache.get(ctx, vpcUuid, notBefore); e != nil {
+				return nil, nil, e
+			}
+			dc2Uuids, missing = snap.resolve(names)
+		}
+		return dc2Uuids, missing, nil
 	}
+
+	var cond *compute.ListDc2Condition
 	if vpcUuid != "" {
-		req.Condition = &compute.ListDc2Condition{VpcUuids: []string{vpcUuid}}
+		cond = &compute.ListDc2Condition{VpcUuids: []string{vpcUuid}}
 	}
-	resp, e := t.dc2.ListDc2(ctx, req)
-	if e != nil {
-		return nil, fmt.Errorf("list dc2 error %w", e)
+	name2Uuid := make(map[string]string, len(names))
+	for _, n := range names {
+		name2Uuid[n] = ""
 	}
-	if resp.Error.Errno != 0 {
-		return nil, fmt.Errorf("list dc2 error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+	unresolved := len(name2Uuid)
+	e := t.listDc2(ctx, cond, func(d *compute.Dc2Info) bool {
+		if id, ok := name2Uuid[d.GetName()]; ok && id == "" {
+			name2Uuid[d.GetName()] = d.GetDc2Uuid()
+			unresolved--
+		}
+		return unresolved > 0
+	})
+	if e != nil {
+		return nil, nil, e
 	}
 
-	var dc2Uuids []string
-	name2Uuid := make(map[string]string, len(resp.Data))
-	for _, n := range resp.Data {
-		name2Uuid[n.GetName()] = n.GetDc2Uuid()
-	}
+	var dc2Uuids, missing []string
 	for _, m := range names {
-		id, ok := name2Uuid[m]
-		if ok {
+		if id := name2Uuid[m]; id != "" {
 			dc2Uuids = append(dc2Uuids, id)
+		} else {
+			missing = append(missing, m)
 		}
 	}
-	return dc2Uuids, nil
+	return dc2Uuids, missing, nil
 }
 
 func (t *client) getDc2UUIDByIp(ctx context.Context, ip string) (string, error) {
 	klog.V(4).Infof("getting dc2 uuid by %s", ip)
-	req := &compute.ListDc2Request{
-		Start:     0,
-		Limit:     maxDc2,
-		Simplify:  true,
-		Condition: &compute.ListDc2Condition{Ip: ip},
+	if t.cache != nil {
+		snap, e := t.cache.get(ctx, "", time.Time{})
+		if e != nil {
+			return "", e
+		}
+		if id, ok := snap.byIp[ip]; ok {
+			return id, nil
+		}
 	}
-	resp, e := t.dc2.ListDc2(ctx, req)
+
+	var uuid string
+	e := t.listDc2(ctx, &compute.ListDc2Condition{Ip: ip}, func(d *compute.Dc2Info) bool {
+		if d.GetIp() == ip {
+			uuid = d.GetDc2Uuid()
+			return false
+		}
+		return true
+	})
 	if e != nil {
-		return "", fmt.Errorf("get dc2 error %w", e)
+		return "", e
 	}
-	if resp.Error.Errno != 0 {
-		return "", fmt.Errorf("get dc2 error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+	if uuid == "" {
+		return "", fmt.Errorf("dc2 %s %w", ip, NotFound)
 	}
-	for _, d := range resp.Data {
-		if d.GetIp() == ip {
-			return d.GetDc2Uuid(), nil
-		}
+	return uuid, nil
+}
+
+// getDc2UUID resolves the ref by the first non-empty field of uuid, name and ip
+func (t *client) getDc2UUID(ctx context.Context, ref Dc2Ref) (string, error) {
+	switch {
+	case ref.Uuid != "":
+		return ref.Uuid, nil
+	case ref.Name != "":
+		return t.getDc2UUIDByName(ctx, ref.Name)
+	case ref.Ip != "":
+		return t.getDc2UUIDByIp(ctx, ref.Ip)
 	}
-	return "", fmt.Errorf("dc2 %s is not found", ip)
+	return "", fmt.Errorf("empty dc2 ref %w", InvalidArgument)
 }
 
 func (t *client) waitForJob(ctx context.Context, info *base.JobInfo, regionID, zoneID string) (*base.JobInfo, error) {
+	ctx, cancel := t.poll.withTimeout(ctx)
+	defer cancel()
+
+	b := t.poll.backoff()
 	for {
 		if info.Done {
 			return info, nil
 		}
 
 		klog.V(5).Infof("wait for job %+v", *info)
-		time.Sleep(pollInterval) // simply use a constant interval
-		resp, e := t.job.JobResult(ctx, &compute.JobResultRequest{
-			Header:   &base.Header{RegionId: regionID, ZoneId: zoneID},
-			JobUuids: []string{info.JobUuid},
-		})
+		if e := b.wait(ctx); e != nil {
+			return nil, fmt.Errorf("wait for job %s error %w", info.JobUuid, e)
+		}
+		next, e := t.jobs.result(ctx, regionID, zoneID, info.JobUuid)
 		if e != nil {
-			return nil, fmt.Errorf("job result error %w", e)
+			return nil, e
 		}
-		if resp.Error.Errno != 0 {
-			return nil, fmt.Errorf("job result error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		info = next
+	}
+}
+
+// pollUntil calls check with backoff of the poll policy, until it returns true or an error, bounded by the policy timeout
+func (t *client) pollUntil(ctx context.Context, what string, check func(context.Context) (bool, error)) error {
+	ctx, cancel := t.poll.withTimeout(ctx)
+	defer cancel()
+
+	b := t.poll.backoff()
+	for {
+		done, e := check(ctx)
+		if e != nil {
+			return e
+		}
+		if done {
+			return nil
+		}
+
+		klog.V(5).Infof("wait for %s", what)
+		if e := b.wait(ctx); e != nil {
+			return fmt.Errorf("wait for %s error %w", what, e)
 		}
-		info = resp.Data[0]
 	}
 }
@@ -1,20 +1,77 @@
 package pkg
 
+import (
+	"sync/atomic"
+
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+)
+
 type mockClient struct {
+	closed int32
+	dc2    *mockDc2Client // shared, so that ebs could be attached to dc2 created by it
 }
 
 func NewMock() (Client, error) {
-	return &mockClient{}, nil
+	c := &mockClient{}
+	c.dc2 = &mockDc2Client{
+		dc2:    make(map[string]*compute.Dc2Info),
+		client: c,
+	}
+	return c, nil
+}
+
+func (t *mockClient) Close() error {
+	atomic.StoreInt32(&t.closed, 1)
+	return nil
+}
+
+func (t *mockClient) checkClosed() error {
+	if atomic.LoadInt32(&t.closed) != 0 {
+		return Closed
+	}
+	return nil
+}
+
+func (t *mockClient) Dc2() Dc2Client {
+	return t.dc2
+}
+
+func (t *mockClient) Eip() EipClient {
+	return &mockEipClient{
+		eip:    make(map[string]*compute.EipInfo),
+		client: t,
+	}
+}
+
+func (t *mockClient) Vpc() VpcClient {
+	return &mockVpcClient{
+		vpc:    make(map[string]*vpcInfo),
+		client: t,
+	}
+}
+
+func (t *mockClient) Sg() SgClient {
+	return &mockSgClient{
+		sg:     make(map[string]*sgInfo),
+		client: t,
+	}
+}
+
+func (t *mockClient) InvalidateDc2Cache() {
 }
 
 func (t *mockClient) Ebs() EbsClient {
 	return &mockEbsClient{
-		ebs: make(map[string]*ebsInfo),
+		ebs:    make(map[string]*ebsInfo),
+		snaps:  make(map[string]*compute.SnapInfo),
+		client: t,
 	}
 }
 
 func (t *mockClient) Slb(vpcUuid string) SlbClient {
 	return &mockSlbClient{
-		slb: make(map[string]*slbInfo),
+		slb:     make(map[string]*slbInfo),
+		vpcUuid: vpcUuid,
+		client:  t,
 	}
 }
@@ -0,0 +1,166 @@
+package pkg
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakeEbsServer answers GetEbsByUuid with an ebs named by the metadata of the request
+type fakeEbsServer struct {
+	compute.UnimplementedEbsServer
+}
+
+func (*fakeEbsServer) GetEbsByUuid(ctx context.Context, req *compute.GetEbsByUuidRequest) (*compute.GetEbsByUuidResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return &compute.GetEbsByUuidResponse{
+		Error: &base.Error{},
+		Data: []*compute.EbsInfo{{
+			EbsUuid: req.EbsUuid,
+			Name:    strings.Join(md.Get("authorization"), ",") + "|" + strings.Join(md.Get("user-agent"), ","),
+		}},
+	}, nil
+}
+
+// serveFakeEbs returns the address of a fake ebs server, and a func to stop it
+func serveFakeEbs(t *testing.T, opts ...grpc.ServerOption) (string, func()) {
+	lis, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	s := grpc.NewServer(opts...)
+	compute.RegisterEbsServer(s, &fakeEbsServer{})
+	go s.Serve(lis)
+	return lis.Addr().String(), s.Stop
+}
+
+func getFakeEbs(t *testing.T, cfg *Config) string {
+	c, e := New(cfg)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, e := c.Ebs().Get(ctx, "ebs-1")
+	if e != nil {
+		t.Fatal(e)
+	}
+	return info.GetName()
+}
+
+func TestInsecureEndpoint(t *testing.T) {
+	addr, stop := serveFakeEbs(t)
+	defer stop()
+	got := getFakeEbs(t, &Config{Token: "secret", Endpoint: addr, Insecure: true, UserAgent: "test-agent"})
+
+	if !strings.HasPrefix(got, "Bearer secret|") {
+		t.Errorf("token not sent, got %q", got)
+	}
+	if !strings.Contains(got, "test-agent") {
+		t.Errorf("user agent not sent, got %q", got)
+	}
+}
+
+func TestCAFile(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	cert, caFile := selfSignedCert(t, dir)
+	addr, stop := serveFakeEbs(t, grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+	defer stop()
+
+	if got := getFakeEbs(t, &Config{Token: "secret", Endpoint: addr, CAFile: caFile}); !strings.HasPrefix(got, "Bearer secret|") {
+		t.Errorf("token not sent, got %q", got)
+	}
+}
+
+func TestCAFileInvalid(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	f := filepath.Join(dir, "ca.pem")
+	if e := ioutil.WriteFile(f, []byte("not a pem"), 0600); e != nil {
+		t.Fatal(e)
+	}
+	if _, e := New(&Config{CAFile: f}); e == nil {
+		t.Error("expect an error of invalid ca file")
+	}
+	if _, e := New(&Config{CAFile: filepath.Join(dir, "missing.pem")}); !os.IsNotExist(unwrapAll(e)) {
+		t.Errorf("expect a not exist error, got %v", e)
+	}
+}
+
+func unwrapAll(e error) error {
+	for {
+		u, ok := e.(interface{ Unwrap() error })
+		if !ok {
+			return e
+		}
+		e = u.Unwrap()
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, e := ioutil.TempDir("", "didiyun-client")
+	if e != nil {
+		t.Fatal(e)
+	}
+	return dir
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1, and a ca file of it in dir
+func selfSignedCert(t *testing.T, dir string) (tls.Certificate, string) {
+	key, e := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if e != nil {
+		t.Fatal(e)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake didiyun"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, e := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if e != nil {
+		t.Fatal(e)
+	}
+	keyDer, e := x509.MarshalECPrivateKey(key)
+	if e != nil {
+		t.Fatal(e)
+	}
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	cert, e := tls.X509KeyPair(certPem, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	caFile := filepath.Join(dir, "ca.pem")
+	if e := ioutil.WriteFile(caFile, certPem, 0600); e != nil {
+		t.Fatal(e)
+	}
+	return cert, caFile
+}
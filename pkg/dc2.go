@@ -0,0 +1,347 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"k8s.io/klog"
+)
+
+type Dc2Client interface {
+	Create(ctx context.Context, regionID, zoneID string, opts *CreateDc2Options) (string, error)
+	Get(ctx context.Context, dc2UUID string) (*compute.Dc2Info, error)
+	List(ctx context.Context, regionID string, filter *Dc2Filter) ([]*compute.Dc2Info, error)
+	Start(ctx context.Context, dc2UUID string) error
+	Stop(ctx context.Context, dc2UUID string) error
+	Restart(ctx context.Context, dc2UUID string) error
+	Reinstall(ctx context.Context, dc2UUID string, opts *ReinstallDc2Options) error
+	ChangeSpec(ctx context.Context, dc2UUID, model string) error
+	Delete(ctx context.Context, dc2UUID string) error
+}
+
+// CreateDc2Options describes a dc2 to create, either Password or PubKeyUuids is required to login
+type CreateDc2Options struct {
+	Name string
+	// ImgUuid is the image of the system disk
+	ImgUuid string
+	// Model is the spec of the instance
+	Model       string
+	Password    string
+	PubKeyUuids []string
+	// SubnetUuid is a subnet of the vpc to place the instance in, the default vpc is used if empty
+	SubnetUuid string
+	SgUuids    []string
+	// RootDiskType and RootDiskSize (GB) of the system disk, defaults of the image are used if empty
+	RootDiskType string
+	RootDiskSize int32
+	Tags         []string
+	UserData     string
+}
+
+type ReinstallDc2Options struct {
+	ImgUuid     string
+	Password    string
+	PubKeyUuids []string
+}
+
+// Dc2Filter selects dc2 to list, empty fields match all
+type Dc2Filter struct {
+	Name    string // exact name
+	Ip      string
+	VpcUuid string
+	SgUuid  string
+	Uuids   []string
+}
+
+// Dc2Ref refers to a dc2 by any of its uuid, name or ip, the first non-empty one in this order is used
+type Dc2Ref struct {
+	Uuid string
+	Name string
+	Ip   string
+}
+
+func (r Dc2Ref) String() string {
+	switch {
+	case r.Uuid != "":
+		return r.Uuid
+	case r.Name != "":
+		return r.Name
+	}
+	return r.Ip
+}
+
+// match tells whether the dc2 is the one referred to
+func (r Dc2Ref) match(info *compute.Dc2Info) bool {
+	switch {
+	case r.Uuid != "":
+		return info.GetDc2Uuid() == r.Uuid
+	case r.Name != "":
+		return info.GetName() == r.Name
+	case r.Ip != "":
+		return info.GetIp() == r.Ip
+	}
+	return false
+}
+
+type dc2Client struct {
+	cli compute.Dc2Client
+	helper
+}
+
+var _ Dc2Client = (*dc2Client)(nil)
+
+// Create creates a dc2 and waits for the job, if the job fails after the dc2 is created,
+// its uuid is returned together with the error
+func (t *dc2Client) Create(ctx context.Context, regionID, zoneID string, opts *CreateDc2Options) (string, error) {
+	if opts == nil {
+		return "", fmt.Errorf("nil dc2 options %w", InvalidArgument)
+	}
+	klog.V(4).Infof("creating dc2 %s, model %s", opts.Name, opts.Model)
+	req := &compute.CreateDc2Request{
+		Header:       &base.Header{RegionId: regionID, ZoneId: zoneID},
+		Count:        1,
+		AutoContinue: false,
+		PayPeriod:    0,
+		Name:         opts.Name,
+		ImgUuid:      opts.ImgUuid,
+		Dc2Model:     opts.Model,
+		Password:     opts.Password,
+		PubKeyUuids:  opts.PubKeyUuids,
+		SubnetUuid:   opts.SubnetUuid,
+		SgUuids:      opts.SgUuids,
+		RootDiskType: opts.RootDiskType,
+		RootDiskSize: opts.RootDiskSize,
+		Tags:         opts.Tags,
+		UserData:     opts.UserData,
+	}
+	resp, e := t.cli.CreateDc2(ctx, req)
+	if e != nil {
+		return "", fmt.Errorf("create dc2 error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return "", newAPIError("create dc2", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], regionID, zoneID)
+	if e != nil {
+		return "", e
+	}
+	t.InvalidateDc2Cache()
+	if !job.Success { // uuid is returned if created anyway, so that it could be cleaned up
+		return job.ResourceUuid, newJobError("create dc2", job)
+	}
+	return job.ResourceUuid, nil
+}
+
+func (t *dc2Client) Get(ctx context.Context, dc2UUID string) (*compute.Dc2Info, error) {
+	klog.V(4).Infof("get dc2 %s", dc2UUID)
+	resp, e := t.cli.GetDc2ByUuid(ctx, &compute.GetDc2ByUuidRequest{
+		Dc2Uuid: dc2UUID,
+	})
+	if e != nil {
+		return nil, fmt.Errorf("get dc2 by uuid: %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return nil, newAPIError("get dc2 by uuid", resp.Error)
+	}
+
+	infos := resp.GetData()
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("dc2 %s %w", dc2UUID, NotFound)
+	}
+	if len(infos) > 1 {
+		return nil, fmt.Errorf("get dc2 by uuid, got too much: %v", infos)
+	}
+	return infos[0], nil
+}
+
+func (t *dc2Client) List(ctx context.Context, regionID string, filter *Dc2Filter) ([]*compute.Dc2Info, error) {
+	klog.V(4).Infof("listing dc2 in region %s", regionID)
+	var cond *compute.ListDc2Condition
+	if filter != nil {
+		cond = &compute.ListDc2Condition{
+			Dc2Name:  filter.Name,
+			Ip:       filter.Ip,
+			VpcUuid:  filter.VpcUuid,
+			SgUuid:   filter.SgUuid,
+			Dc2Uuids: filter.Uuids,
+		}
+	}
+
+	var dc2s []*compute.Dc2Info
+	e := listPages(maxDc2, func(start, limit int32) (int, error) {
+		resp, e := t.cli.ListDc2(ctx, &compute.ListDc2Request{
+			Header:    &base.Header{RegionId: regionID},
+			Start:     start,
+			Limit:     limit,
+			Condition: cond,
+		})
+		if e != nil {
+			return 0, fmt.Errorf("list dc2 error %w", e)
+		}
+		if resp.Error.Errno != 0 {
+			return 0, newAPIError("list dc2", resp.Error)
+		}
+		for _, d := range resp.Data {
+			if filter != nil && filter.Name != "" && d.GetName() != filter.Name { // name condition is fuzzy
+				continue
+			}
+			dc2s = append(dc2s, d)
+		}
+		return len(resp.Data), nil
+	})
+	if e != nil {
+		return nil, e
+	}
+	return dc2s, nil
+}
+
+func (t *dc2Client) Start(ctx context.Context, dc2UUID string) error {
+	klog.V(4).Infof("starting dc2 %s", dc2UUID)
+	req := &compute.StartDc2Request{
+		Dc2: []*compute.StartDc2Request_Input{{Dc2Uuid: dc2UUID}},
+	}
+	resp, e := t.cli.StartDc2(ctx, req)
+	if e != nil {
+		return fmt.Errorf("start dc2 error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("start dc2", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success {
+		return newJobError("start dc2", job)
+	}
+	return nil
+}
+
+func (t *dc2Client) Stop(ctx context.Context, dc2UUID string) error {
+	klog.V(4).Infof("stopping dc2 %s", dc2UUID)
+	req := &compute.StopDc2Request{
+		Dc2: []*compute.StopDc2Request_Input{{Dc2Uuid: dc2UUID}},
+	}
+	resp, e := t.cli.StopDc2(ctx, req)
+	if e != nil {
+		return fmt.Errorf("stop dc2 error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("stop dc2", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success {
+		return newJobError("stop dc2", job)
+	}
+	return nil
+}
+
+func (t *dc2Client) Restart(ctx context.Context, dc2UUID string) error {
+	klog.V(4).Infof("restarting dc2 %s", dc2UUID)
+	req := &compute.RebootDc2Request{
+		Dc2: []*compute.RebootDc2Request_Input{{Dc2Uuid: dc2UUID}},
+	}
+	resp, e := t.cli.RebootDc2(ctx, req)
+	if e != nil {
+		return fmt.Errorf("restart dc2 error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("restart dc2", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success {
+		return newJobError("restart dc2", job)
+	}
+	return nil
+}
+
+func (t *dc2Client) Reinstall(ctx context.Context, dc2UUID string, opts *ReinstallDc2Options) error {
+	if opts == nil {
+		return fmt.Errorf("nil reinstall options %w", InvalidArgument)
+	}
+	klog.V(4).Infof("reinstalling dc2 %s with image %s", dc2UUID, opts.ImgUuid)
+	req := &compute.ReinstallDc2SystemRequest{
+		Dc2: []*compute.ReinstallDc2SystemRequest_Input{{
+			Dc2Uuid:     dc2UUID,
+			ImgUuid:     opts.ImgUuid,
+			Password:    opts.Password,
+			PubKeyUuids: opts.PubKeyUuids,
+		}},
+	}
+	resp, e := t.cli.ReinstallDc2System(ctx, req)
+	if e != nil {
+		return fmt.Errorf("reinstall dc2 error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("reinstall dc2", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success {
+		return newJobError("reinstall dc2", job)
+	}
+	return nil
+}
+
+func (t *dc2Client) ChangeSpec(ctx context.Context, dc2UUID, model string) error {
+	klog.V(4).Infof("changing spec of dc2 %s to %s", dc2UUID, model)
+	req := &compute.ChangeDc2SpecRequest{
+		Dc2: []*compute.ChangeDc2SpecRequest_Input{{Dc2Uuid: dc2UUID, Dc2Model: model}},
+	}
+	resp, e := t.cli.ChangeDc2Spec(ctx, req)
+	if e != nil {
+		return fmt.Errorf("change dc2 spec error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("change dc2 spec", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success {
+		return newJobError("change dc2 spec", job)
+	}
+	return nil
+}
+
+// Delete destroys the dc2
+func (t *dc2Client) Delete(ctx context.Context, dc2UUID string) error {
+	klog.V(4).Infof("deleting dc2 %s", dc2UUID)
+	req := &compute.DestroyDc2Request{
+		Dc2: []*compute.DestroyDc2Request_Input{{Dc2Uuid: dc2UUID}},
+	}
+	resp, e := t.cli.DestroyDc2(ctx, req)
+	if e != nil {
+		return fmt.Errorf("delete dc2 error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("delete dc2", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	t.InvalidateDc2Cache()
+	if !job.Success { // not found etc.
+		return newJobError("delete dc2", job)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"github.com/pborman/uuid"
+)
+
+type mockDc2Client struct {
+	dc2    map[string]*compute.Dc2Info
+	count  int
+	client *mockClient
+}
+
+var _ Dc2Client = (*mockDc2Client)(nil)
+
+func (t *mockDc2Client) Create(ctx context.Context, regionID, zoneID string, opts *CreateDc2Options) (string, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return "", e
+	}
+	if opts == nil {
+		return "", fmt.Errorf("nil dc2 options %w", InvalidArgument)
+	}
+	id := uuid.NewUUID().String()
+	t.count++
+	t.dc2[id] = &compute.Dc2Info{
+		Dc2Uuid: id,
+		Name:    opts.Name,
+		Ip:      fmt.Sprintf("10.0.0.%d", t.count),
+		Status:  "running",
+		ImgUuid: opts.ImgUuid,
+		Tags:    opts.Tags,
+	}
+	return id, nil
+}
+
+func (t *mockDc2Client) Get(ctx context.Context, dc2UUID string) (*compute.Dc2Info, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	d, ok := t.dc2[dc2UUID]
+	if !ok {
+		return nil, fmt.Errorf("dc2 %s %w", dc2UUID, NotFound)
+	}
+	return d, nil
+}
+
+func (t *mockDc2Client) List(ctx context.Context, regionID string, filter *Dc2Filter) ([]*compute.Dc2Info, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	var dc2s []*compute.Dc2Info
+	for _, d := range t.dc2 {
+		if filter != nil && (filter.Name != "" && d.Name != filter.Name || filter.Ip != "" && d.Ip != filter.Ip) {
+			continue
+		}
+		dc2s = append(dc2s, d)
+	}
+	return dc2s, nil
+}
+
+func (t *mockDc2Client) Start(ctx context.Context, dc2UUID string) error {
+	return t.setStatus(dc2UUID, "running")
+}
+
+func (t *mockDc2Client) Stop(ctx context.Context, dc2UUID string) error {
+	return t.setStatus(dc2UUID, "stopped")
+}
+
+func (t *mockDc2Client) Restart(ctx context.Context, dc2UUID string) error {
+	return t.setStatus(dc2UUID, "running")
+}
+
+func (t *mockDc2Client) Reinstall(ctx context.Context, dc2UUID string, opts *ReinstallDc2Options) error {
+	if opts == nil {
+		return fmt.Errorf("nil reinstall options %w", InvalidArgument)
+	}
+	if e := t.setStatus(dc2UUID, "running"); e != nil {
+		return e
+	}
+	t.dc2[dc2UUID].ImgUuid = opts.ImgUuid
+	return nil
+}
+
+func (t *mockDc2Client) ChangeSpec(ctx context.Context, dc2UUID, model string) error {
+	return t.setStatus(dc2UUID, "running")
+}
+
+func (t *mockDc2Client) Delete(ctx context.Context, dc2UUID string) error {
+	if e := t.client.checkClosed(); e != nil {
+		return e
+	}
+	if _, ok := t.dc2[dc2UUID]; !ok {
+		return fmt.Errorf("dc2 %s %w", dc2UUID, NotFound)
+	}
+	delete(t.dc2, dc2UUID)
+	return nil
+}
+
+// find returns the dc2 referred to, like the real client resolves a Dc2Ref
+func (t *mockDc2Client) find(ref Dc2Ref) (*compute.Dc2Info, error) {
+	if ref == (Dc2Ref{}) {
+		return nil, fmt.Errorf("empty dc2 ref %w", InvalidArgument)
+	}
+	for _, d := range t.dc2 {
+		if ref.match(d) {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("dc2 %s %w", ref, NotFound)
+}
+
+func (t *mockDc2Client) setStatus(dc2UUID, status string) error {
+	if e := t.client.checkClosed(); e != nil {
+		return e
+	}
+	d, ok := t.dc2[dc2UUID]
+	if !ok {
+		return fmt.Errorf("dc2 %s %w", dc2UUID, NotFound)
+	}
+	d.Status = status
+	return nil
+}
@@ -0,0 +1,36 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"google.golang.org/grpc"
+)
+
+func (t *fakeDc2Client) CreateDc2(ctx context.Context, req *compute.CreateDc2Request, opts ...grpc.CallOption) (*compute.CreateDc2Response, error) {
+	return &compute.CreateDc2Response{
+		Error: &base.Error{},
+		Data:  []*base.JobInfo{{Done: true, ResourceUuid: "dc2-1", Result: "boot failed"}},
+	}, nil
+}
+
+func TestCreateDc2Failed(t *testing.T) {
+	dc2 := &dc2Client{cli: &fakeDc2Client{}, helper: &client{}}
+	ctx := context.Background()
+
+	id, e := dc2.Create(ctx, "gz", "gz02", &CreateDc2Options{Name: "node-1"})
+	var ae *APIError
+	if id != "dc2-1" || !errors.As(e, &ae) || ae.Job == nil {
+		t.Errorf("expect the uuid with the job error, got %q, %v", id, e)
+	}
+
+	if _, e := dc2.Create(ctx, "gz", "gz02", nil); !errors.Is(e, InvalidArgument) {
+		t.Errorf("expect InvalidArgument of nil options, got %v", e)
+	}
+	if e := dc2.Reinstall(ctx, "dc2-1", nil); !errors.Is(e, InvalidArgument) {
+		t.Errorf("expect InvalidArgument of nil options, got %v", e)
+	}
+}
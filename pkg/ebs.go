@@ -3,48 +3,235 @@ package pkg
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/didiyun/didiyun-go-sdk/base/v1"
 	"github.com/didiyun/didiyun-go-sdk/compute/v1"
 	"k8s.io/klog"
 )
 
+const (
+	maxEbs = 100
+
+	// clientTokenTagPrefix tags an ebs with the client token it is created by
+	clientTokenTagPrefix = "client-token:"
+)
+
+// EbsType is the disk type of an ebs
+type EbsType string
+
+const (
+	EbsTypeSSD EbsType = "SSD"
+	EbsTypeHE  EbsType = "HE" // high efficiency
+)
+
+// EbsSizeLimit is the range of ebs size of a disk type, size must be a multiple of StepGB
+type EbsSizeLimit struct {
+	MinGB  int64
+	MaxGB  int64
+	StepGB int64
+}
+
+var ebsSizeLimits = map[EbsType]EbsSizeLimit{
+	EbsTypeSSD: {MinGB: 20, MaxGB: 16380, StepGB: 10},
+	EbsTypeHE:  {MinGB: 20, MaxGB: 16380, StepGB: 10},
+}
+
+// SizeLimit returns the size limit of the disk type, false if the type is unknown
+func (t EbsType) SizeLimit() (EbsSizeLimit, bool) {
+	l, ok := ebsSizeLimits[t]
+	return l, ok
+}
+
+func (t EbsType) validate(sizeGB int64) error {
+	l, ok := t.SizeLimit()
+	if !ok {
+		return fmt.Errorf("unknown ebs type %q %w", t, InvalidArgument)
+	}
+	if sizeGB < l.MinGB || sizeGB > l.MaxGB || sizeGB%l.StepGB != 0 {
+		return fmt.Errorf("size %d GB of %s ebs not in [%d, %d] by step %d %w", sizeGB, t, l.MinGB, l.MaxGB, l.StepGB, InvalidArgument)
+	}
+	return nil
+}
+
 type EbsClient interface {
-	Create(ctx context.Context, regionID, zoneID, name, typ string, sizeGB int64) (string, error)
+	Create(ctx context.Context, regionID, zoneID, name string, typ EbsType, sizeGB int64) (string, error)
+	CreateWithOptions(ctx context.Context, regionID, zoneID string, opts *CreateEbsOptions) (string, error)
+	CreateOrGet(ctx context.Context, regionID, zoneID, name string, typ EbsType, sizeGB int64, token string) (string, error)
 	Get(ctx context.Context, ebsUUID string) (*compute.EbsInfo, error)
+	List(ctx context.Context, filter *EbsFilter) ([]*compute.EbsInfo, error)
 	Delete(ctx context.Context, ebsUUID string) error
 	Attach(ctx context.Context, ebsUUID, dc2Ip string) (string, error)
+	AttachTo(ctx context.Context, ebsUUID string, dc2 Dc2Ref) (string, error)
 	Detach(ctx context.Context, ebsUUID string) error
+	DetachFrom(ctx context.Context, ebsUUID string, dc2 Dc2Ref) error
 	Expand(ctx context.Context, ebsUUID string, sizeGB int64) error
+
+	DeleteMany(ctx context.Context, ebsUUIDs []string) (map[string]error, error)
+	AttachMany(ctx context.Context, attachments map[string]Dc2Ref) (map[string]*AttachResult, error)
+	DetachMany(ctx context.Context, ebsUUIDs []string) (map[string]error, error)
+	ExpandMany(ctx context.Context, sizes map[string]int64) (map[string]error, error)
+
+	WaitFor(ctx context.Context, ebsUUID string, cond EbsPredicate) (*compute.EbsInfo, error)
+
+	CreateSnapshot(ctx context.Context, ebsUUID, name string) (string, error)
+	ListSnapshots(ctx context.Context, ebsUUID string) ([]*compute.SnapInfo, error)
+	DeleteSnapshot(ctx context.Context, snapUUID string) error
+	CreateFromSnapshot(ctx context.Context, regionID, zoneID, name, snapUUID string, typ EbsType, sizeGB int64) (string, error)
+}
+
+// EbsPredicate tells whether an ebs reaches the state waited for, an error stops waiting at a terminal state
+type EbsPredicate func(*compute.EbsInfo) (bool, error)
+
+// EbsAttachedTo holds when the ebs is attached to the dc2 of the uuid.
+// It takes a uuid rather than a Dc2Ref, since the name and ip of the dc2 nested in an ebs are not reliable to compare
+func EbsAttachedTo(dc2UUID string) EbsPredicate {
+	return func(info *compute.EbsInfo) (bool, error) {
+		return info.GetDc2() != nil && info.GetDc2().GetDc2Uuid() == dc2UUID, nil
+	}
+}
+
+// EbsDetached holds when the ebs is not attached to any dc2
+func EbsDetached(info *compute.EbsInfo) (bool, error) {
+	return info.GetDc2() == nil, nil
+}
+
+// CreateEbsOptions describes an ebs to create, encryption is not supported by the api
+type CreateEbsOptions struct {
+	Name   string
+	Type   EbsType
+	SizeGB int64
+	// PayPeriod is the prepaid months, pay by hour if 0, AutoContinue renews a prepaid ebs when it expires
+	PayPeriod    int32
+	AutoContinue bool
+	CouponId     string
+	Tags         []string
+	// SnapUuid is a snapshot to restore data from, in the same region and zone
+	SnapUuid string
+	// Dc2 is attached to once created if not nil
+	Dc2 *Dc2Ref
+}
+
+// EbsFilter selects ebs to list, RegionID is required, other empty fields match all
+type EbsFilter struct {
+	RegionID   string
+	ZoneID     string
+	Name       string // exact name
+	NamePrefix string
+	Dc2Uuid    string // attached to the dc2
+	Type       EbsType
+	// Attached and NotAttached select by attachment state, setting neither matches both
+	Attached    bool
+	NotAttached bool
+}
+
+func (f *EbsFilter) match(info *compute.EbsInfo) bool {
+	if f.Name != "" && info.GetName() != f.Name {
+		return false
+	}
+	if f.NamePrefix != "" && !strings.HasPrefix(info.GetName(), f.NamePrefix) {
+		return false
+	}
+	if f.Type != "" && !strings.EqualFold(info.GetType(), string(f.Type)) {
+		return false
+	}
+	attached := info.GetDc2() != nil
+	if f.Attached && !attached || f.NotAttached && attached {
+		return false
+	}
+	return true
 }
 
 type ebsClient struct {
-	cli compute.EbsClient
+	cli  compute.EbsClient
+	snap compute.SnapClient
 	helper
 }
 
 var _ EbsClient = (*ebsClient)(nil)
 
-func (t *ebsClient) Create(ctx context.Context, regionID, zoneID, name, typ string, sizeGB int64) (string, error) {
-	klog.V(4).Infof("creating ebs %s, type %s, size %d GB", name, typ, sizeGB)
+func (t *ebsClient) Create(ctx context.Context, regionID, zoneID, name string, typ EbsType, sizeGB int64) (string, error) {
+	return t.CreateWithOptions(ctx, regionID, zoneID, &CreateEbsOptions{Name: name, Type: typ, SizeGB: sizeGB})
+}
+
+func (t *ebsClient) CreateWithOptions(ctx context.Context, regionID, zoneID string, opts *CreateEbsOptions) (string, error) {
+	if opts == nil {
+		return "", fmt.Errorf("nil ebs options %w", InvalidArgument)
+	}
+	// validated before resolving dc2, and before calling the api
+	if e := opts.Type.validate(opts.SizeGB); e != nil {
+		return "", e
+	}
+	klog.V(4).Infof("creating ebs %s, type %s, size %d GB, pay period %d, snapshot %q", opts.Name, opts.Type, opts.SizeGB, opts.PayPeriod, opts.SnapUuid)
 	req := &compute.CreateEbsRequest{
 		Header:       &base.Header{RegionId: regionID, ZoneId: zoneID},
 		Count:        1,
-		AutoContinue: false,
-		PayPeriod:    0,
-		Name:         name,
-		Size:         sizeGB,
-		DiskType:     typ,
+		AutoContinue: opts.AutoContinue,
+		PayPeriod:    opts.PayPeriod,
+		CouponId:     opts.CouponId,
+		Name:         opts.Name,
+		Size:         opts.SizeGB,
+		DiskType:     string(opts.Type),
+		SnapUuid:     opts.SnapUuid,
+		Tags:         opts.Tags,
+	}
+	if opts.Dc2 != nil {
+		dc2UUID, e := t.getDc2UUID(ctx, *opts.Dc2)
+		if e != nil {
+			return "", e
+		}
+		req.Dc2Uuid = dc2UUID
+	}
+	return t.create(ctx, req)
+}
+
+// CreateOrGet creates an ebs unless one with the same client token, or the same name if token is empty,
+// already exists in the zone. The existing one is returned if its type and size match, otherwise an
+// AlreadyExists error is returned.
+func (t *ebsClient) CreateOrGet(ctx context.Context, regionID, zoneID, name string, typ EbsType, sizeGB int64, token string) (string, error) {
+	klog.V(4).Infof("creating or getting ebs %s, type %s, size %d GB, token %q", name, typ, sizeGB, token)
+	// validated before listing, or an existing ebs may be returned for a type create rejects, eg. "ssd"
+	if e := typ.validate(sizeGB); e != nil {
+		return "", e
 	}
+	var existing []*compute.EbsInfo
+	e := t.listEbs(ctx, regionID, zoneID, nil, func(info *compute.EbsInfo) bool {
+		if token != "" && hasTag(info.GetEbsTags(), clientTokenTag(token)) || token == "" && info.GetName() == name {
+			existing = append(existing, info)
+		}
+		return true
+	})
+	if e != nil {
+		return "", e
+	}
+	for _, info := range existing {
+		if strings.EqualFold(info.GetType(), string(typ)) && info.GetSize() == sizeGB<<30 {
+			klog.V(4).Infof("ebs %s already exists as %s", name, info.GetEbsUuid())
+			return info.GetEbsUuid(), nil
+		}
+	}
+	if len(existing) > 0 {
+		info := existing[0]
+		return "", fmt.Errorf("ebs %s exists as %s, type %s, size %d GiB: %w", name, info.GetEbsUuid(), info.GetType(), info.GetSize()>>30, AlreadyExists)
+	}
+
+	opts := &CreateEbsOptions{Name: name, Type: typ, SizeGB: sizeGB}
+	if token != "" {
+		opts.Tags = []string{clientTokenTag(token)}
+	}
+	return t.CreateWithOptions(ctx, regionID, zoneID, opts)
+}
+
+func (t *ebsClient) create(ctx context.Context, req *compute.CreateEbsRequest) (string, error) {
 	resp, e := t.cli.CreateEbs(ctx, req)
 	if e != nil {
 		return "", fmt.Errorf("create ebs error %w", e)
 	}
 	if resp.Error.Errno != 0 {
-		return "", fmt.Errorf("create ebs error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return "", newAPIError("create ebs", resp.Error)
 	}
 
-	job, e := t.waitForJob(ctx, resp.Data[0], regionID, zoneID)
+	job, e := t.waitForJob(ctx, resp.Data[0], req.Header.GetRegionId(), req.Header.GetZoneId())
 	if e != nil {
 		return "", e
 	}
@@ -52,11 +239,51 @@ func (t *ebsClient) Create(ctx context.Context, regionID, zoneID, name, typ stri
 		if job.ResourceUuid != "" { // not success, but still got uuid that already created
 			return job.ResourceUuid, nil
 		}
-		return "", fmt.Errorf("failed to create ebs: %s", job.Result)
+		return "", newJobError("create ebs", job)
 	}
 	return job.ResourceUuid, nil
 }
 
+// listEbs visits all ebs in the zone, until visit returns false
+func (t *ebsClient) listEbs(ctx context.Context, regionID, zoneID string, cond *compute.ListEbsCondition, visit func(*compute.EbsInfo) bool) error {
+	return listPages(maxEbs, func(start, limit int32) (int, error) {
+		resp, e := t.cli.ListEbs(ctx, &compute.ListEbsRequest{
+			Header:    &base.Header{RegionId: regionID, ZoneId: zoneID},
+			Start:     start,
+			Limit:     limit,
+			Condition: cond,
+		})
+		if e != nil {
+			return 0, fmt.Errorf("list ebs error %w", e)
+		}
+		if resp.Error.Errno != 0 {
+			return 0, newAPIError("list ebs", resp.Error)
+		}
+		for _, info := range resp.Data {
+			if zoneID != "" && info.GetRegion().GetZone().GetId() != "" && info.GetRegion().GetZone().GetId() != zoneID {
+				continue
+			}
+			if !visit(info) {
+				return 0, stopPaging
+			}
+		}
+		return len(resp.Data), nil
+	})
+}
+
+func clientTokenTag(token string) string {
+	return clientTokenTagPrefix + token
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *ebsClient) Get(ctx context.Context, ebsUUID string) (*compute.EbsInfo, error) {
 	klog.V(4).Infof("get ebs %s", ebsUUID)
 	resp, e := t.cli.GetEbsByUuid(ctx, &compute.GetEbsByUuidRequest{
@@ -66,12 +293,12 @@ func (t *ebsClient) Get(ctx context.Context, ebsUUID string) (*compute.EbsInfo,
 		return nil, fmt.Errorf("get ebs by uuid: %w", e)
 	}
 	if resp.Error.Errno != 0 {
-		return nil, fmt.Errorf("get ebs by uuid error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return nil, newAPIError("get ebs by uuid", resp.Error)
 	}
 
 	infos := resp.GetData()
 	if len(infos) == 0 {
-		return nil, fmt.Errorf("get ebs by uuid, got nothing")
+		return nil, fmt.Errorf("ebs %s %w", ebsUUID, NotFound)
 	}
 	if len(infos) > 1 {
 		return nil, fmt.Errorf("get ebs by uuid, got too much: %v", infos)
@@ -80,34 +307,45 @@ func (t *ebsClient) Get(ctx context.Context, ebsUUID string) (*compute.EbsInfo,
 	return infos[0], nil
 }
 
-func (t *ebsClient) attachedDevice(ctx context.Context, ebsUUID, dc2Ip string) (string, error) {
-	klog.V(4).Infof("getting ebs %s", ebsUUID)
-	req := &compute.GetEbsByUuidRequest{
-		EbsUuid: ebsUUID,
+func (t *ebsClient) List(ctx context.Context, filter *EbsFilter) ([]*compute.EbsInfo, error) {
+	if filter == nil || filter.RegionID == "" {
+		return nil, fmt.Errorf("list ebs requires region %w", InvalidArgument)
 	}
-	resp, e := t.cli.GetEbsByUuid(ctx, req)
-	if e != nil {
-		return "", fmt.Errorf("get ebs error %w", e)
+	klog.V(4).Infof("listing ebs in region %s, zone %s", filter.RegionID, filter.ZoneID)
+	var cond *compute.ListEbsCondition
+	if filter.Dc2Uuid != "" {
+		cond = &compute.ListEbsCondition{Dc2Uuids: []string{filter.Dc2Uuid}}
 	}
-	if resp.Error.Errno != 0 {
-		return "", fmt.Errorf("get ebs error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+
+	var ebs []*compute.EbsInfo
+	e := t.listEbs(ctx, filter.RegionID, filter.ZoneID, cond, func(info *compute.EbsInfo) bool {
+		if filter.match(info) {
+			ebs = append(ebs, info)
+		}
+		return true
+	})
+	if e != nil {
+		return nil, e
 	}
+	return ebs, nil
+}
 
-	if len(resp.Data) == 0 {
-		klog.V(4).Infof("ebs %s not found", ebsUUID)
-		return "", nil
+func (t *ebsClient) attachedDevice(ctx context.Context, ebsUUID, dc2UUID string) (string, error) {
+	info, e := t.Get(ctx, ebsUUID)
+	if e != nil {
+		return "", e
 	}
 
-	dc2 := resp.Data[0].GetDc2()
+	dc2 := info.GetDc2()
 	if dc2 == nil {
 		klog.V(4).Infof("ebs %s is already detached", ebsUUID)
 		return "", nil
 	}
-	dn := resp.Data[0].GetDeviceName()
+	dn := info.GetDeviceName()
 	klog.V(4).Infof("ebs %s is already attached to %s, device %s", ebsUUID, dc2.GetName(), dn)
 
 	// not attached to target dc2
-	if dc2Ip != "" && dc2.GetIp() != dc2Ip {
+	if dc2UUID != "" && dc2.GetDc2Uuid() != dc2UUID {
 		return "", nil
 	}
 	// attached to any dc2
@@ -124,7 +362,7 @@ func (t *ebsClient) Delete(ctx context.Context, ebsUUID string) error {
 		return fmt.Errorf("delete ebs error %w", e)
 	}
 	if resp.Error.Errno != 0 {
-		return fmt.Errorf("delete ebs error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return newAPIError("delete ebs", resp.Error)
 	}
 
 	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
@@ -132,18 +370,19 @@ func (t *ebsClient) Delete(ctx context.Context, ebsUUID string) error {
 		return e
 	}
 	if !job.Success { // not found etc.
-		if job.Result == ebsNotFoundMsg {
-			return fmt.Errorf("failed to delete ebs: %w", NotFound)
-		}
-		return fmt.Errorf("failed to delete ebs: %s", job.Result)
+		return newJobError("delete ebs", job)
 	}
 	return nil
 }
 
 func (t *ebsClient) Attach(ctx context.Context, ebsUUID, dc2Ip string) (string, error) {
-	klog.V(4).Infof("attaching ebs %s to dc2 %s", ebsUUID, dc2Ip)
-	// dc2UUID, e := t.getDc2UUIDByName(ctx, dc2Name)
-	dc2UUID, e := t.getDc2UUIDByIp(ctx, dc2Ip)
+	return t.AttachTo(ctx, ebsUUID, Dc2Ref{Ip: dc2Ip})
+}
+
+// AttachTo attaches the ebs to the dc2, returns the device name
+func (t *ebsClient) AttachTo(ctx context.Context, ebsUUID string, dc2 Dc2Ref) (string, error) {
+	klog.V(4).Infof("attaching ebs %s to dc2 %s", ebsUUID, dc2)
+	dc2UUID, e := t.getDc2UUID(ctx, dc2)
 	if e != nil {
 		return "", e
 	}
@@ -156,7 +395,7 @@ func (t *ebsClient) Attach(ctx context.Context, ebsUUID, dc2Ip string) (string,
 		return "", fmt.Errorf("attach ebs error %w", e)
 	}
 	if resp.Error.Errno != 0 {
-		return "", fmt.Errorf("attach ebs error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return "", newAPIError("attach ebs", resp.Error)
 	}
 
 	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
@@ -165,18 +404,47 @@ func (t *ebsClient) Attach(ctx context.Context, ebsUUID, dc2Ip string) (string,
 	}
 
 	// whether job.Success or not, need check attached device
-	// now check based on getIp ?
-	device, e := t.attachedDevice(ctx, ebsUUID, dc2Ip)
+	device, e := t.attachedDevice(ctx, ebsUUID, dc2UUID)
 	if e != nil {
 		return "", e
 	}
 	if device != "" {
 		return device, nil
 	}
-	return "", fmt.Errorf("failed to attach ebs: %s", job.Result)
+	return "", newJobError("attach ebs", job)
 }
 
 func (t *ebsClient) Detach(ctx context.Context, ebsUUID string) error {
+	return t.detach(ctx, ebsUUID, "")
+}
+
+// DetachFrom detaches the ebs only if it is attached to the dc2, a Conflict error is returned if it is attached to
+// another one, and it is not an error if the ebs is already detached.
+// The api detaches an ebs from whatever dc2 it is attached to, so the check is not atomic: if the ebs is moved to
+// another dc2 between the check and the detach request, it is detached from that one. The ebs is checked again once
+// detached, and a Conflict error is returned if it is attached to another dc2 by then
+func (t *ebsClient) DetachFrom(ctx context.Context, ebsUUID string, dc2 Dc2Ref) error {
+	klog.V(4).Infof("detaching ebs %s from dc2 %s", ebsUUID, dc2)
+	dc2UUID, e := t.getDc2UUID(ctx, dc2)
+	if e != nil {
+		return e
+	}
+	info, e := t.Get(ctx, ebsUUID)
+	if e != nil {
+		return e
+	}
+	if info.GetDc2() == nil {
+		klog.V(4).Infof("ebs %s is already detached", ebsUUID)
+		return nil
+	}
+	if attached := info.GetDc2().GetDc2Uuid(); attached != dc2UUID {
+		return fmt.Errorf("ebs %s is attached to dc2 %s, not %s %w", ebsUUID, attached, dc2, Conflict)
+	}
+	return t.detach(ctx, ebsUUID, dc2UUID)
+}
+
+// detach detaches the ebs, and checks it is detached, or attached to a dc2 other than fromDc2 if not empty
+func (t *ebsClient) detach(ctx context.Context, ebsUUID, fromDc2 string) error {
 	klog.V(4).Infof("detaching ebs %s", ebsUUID)
 	req := &compute.DetachEbsRequest{
 		Ebs: []*compute.DetachEbsRequest_Input{{EbsUuid: ebsUUID}},
@@ -186,7 +454,7 @@ func (t *ebsClient) Detach(ctx context.Context, ebsUUID string) error {
 		return fmt.Errorf("detach ebs error %w", e)
 	}
 	if resp.Error.Errno != 0 {
-		return fmt.Errorf("detach ebs error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return newAPIError("detach ebs", resp.Error)
 	}
 
 	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
@@ -194,38 +462,30 @@ func (t *ebsClient) Detach(ctx context.Context, ebsUUID string) error {
 		return e
 	}
 
-	device, e := t.attachedDevice(ctx, ebsUUID, "")
+	info, e := t.Get(ctx, ebsUUID)
 	if e != nil {
 		return e
 	}
-	if device == "" {
+	dc2 := info.GetDc2()
+	if dc2 == nil {
 		return nil
 	}
-	return fmt.Errorf("failed to detach ebs: %s", job.Result)
+	if fromDc2 != "" && dc2.GetDc2Uuid() != fromDc2 {
+		return fmt.Errorf("ebs %s is attached to dc2 %s after detached from %s %w", ebsUUID, dc2.GetDc2Uuid(), fromDc2, Conflict)
+	}
+	return newJobError("detach ebs", job)
 }
 
 func (t *ebsClient) Expand(ctx context.Context, ebsUUID string, sizeGB int64) error {
 	klog.V(4).Infof("expanding ebs %s", ebsUUID)
 
 	// get to check size
-	ebsResp, e := t.cli.GetEbsByUuid(ctx, &compute.GetEbsByUuidRequest{EbsUuid: ebsUUID})
+	info, e := t.Get(ctx, ebsUUID)
 	if e != nil {
-		return fmt.Errorf("get ebs error %w", e)
-	}
-	if ebsResp.Error.Errno != 0 {
-		return fmt.Errorf("get ebs error %s (%d)", ebsResp.Error.Errmsg, ebsResp.Error.Errno)
-	}
-	if len(ebsResp.Data) == 0 {
-		klog.V(4).Infof("ebs %s not found", ebsUUID)
-		return nil
-	}
-	curSize := ebsResp.Data[0].GetSize()
-	if sizeGB<<30 == curSize {
-		klog.V(4).Infof("not expand due to same size %d GiB", sizeGB)
-		return nil
+		return e
 	}
-	if sizeGB<<30 < curSize {
-		return fmt.Errorf("can not shrink size from %d", curSize)
+	if ok, e := needExpand(info, sizeGB); !ok {
+		return e
 	}
 
 	req := &compute.ChangeEbsSizeRequest{
@@ -236,7 +496,7 @@ func (t *ebsClient) Expand(ctx context.Context, ebsUUID string, sizeGB int64) er
 		return fmt.Errorf("expand ebs error %w", e)
 	}
 	if resp.Error.Errno != 0 {
-		return fmt.Errorf("expand ebs error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return newAPIError("expand ebs", resp.Error)
 	}
 
 	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
@@ -244,7 +504,47 @@ func (t *ebsClient) Expand(ctx context.Context, ebsUUID string, sizeGB int64) er
 		return e
 	}
 	if !job.Success {
-		return fmt.Errorf("failed to expand ebs: %s", job.Result)
+		return newJobError("expand ebs", job)
 	}
 	return nil
 }
+
+// WaitFor polls the ebs until cond holds, returns the last got ebs.
+// It stops with an error if ctx ends, the ebs is not found, cond returns an error, or the last job of the ebs failed,
+// eg. waiting for EbsAttachedTo after an attach job failed. Like waiting for a job, it also gives up once
+// the JobPoll.Timeout of the config passes
+func (t *ebsClient) WaitFor(ctx context.Context, ebsUUID string, cond EbsPredicate) (*compute.EbsInfo, error) {
+	klog.V(4).Infof("waiting for ebs %s", ebsUUID)
+	var info *compute.EbsInfo
+	e := t.pollUntil(ctx, "ebs "+ebsUUID, func(ctx context.Context) (bool, error) {
+		var e error
+		if info, e = t.Get(ctx, ebsUUID); e != nil {
+			return false, e
+		}
+		if ok, e := cond(info); ok || e != nil {
+			return ok, e
+		}
+		if job := info.GetJob(); job.GetDone() && !job.GetSuccess() {
+			return false, newJobError("wait for ebs "+ebsUUID, job)
+		}
+		return false, nil
+	})
+	return info, e
+}
+
+// needExpand tells whether the ebs should be expanded to the size, or an error if it can not be
+func needExpand(info *compute.EbsInfo, sizeGB int64) (bool, error) {
+	curSize := info.GetSize()
+	if sizeGB<<30 == curSize {
+		klog.V(4).Infof("not expand due to same size %d GiB", sizeGB)
+		return false, nil
+	}
+	if sizeGB<<30 < curSize {
+		return false, fmt.Errorf("can not shrink size from %d %w", curSize, InvalidArgument)
+	}
+	// unknown types are rejected as create does
+	if e := EbsType(strings.ToUpper(info.GetType())).validate(sizeGB); e != nil {
+		return false, e
+	}
+	return true, nil
+}
@@ -0,0 +1,255 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"k8s.io/klog"
+)
+
+// AttachResult is the result of attaching one ebs in AttachMany
+type AttachResult struct {
+	Device string
+	Err    error
+}
+
+// DeleteMany deletes the ebs in one request, returns the error of each ebs, nil for deleted ones
+func (t *ebsClient) DeleteMany(ctx context.Context, ebsUUIDs []string) (map[string]error, error) {
+	ebsUUIDs = uniqueStrings(ebsUUIDs)
+	klog.V(4).Infof("deleting %d ebs", len(ebsUUIDs))
+	results := make(map[string]error, len(ebsUUIDs))
+	if len(ebsUUIDs) == 0 {
+		return results, nil
+	}
+	req := &compute.DeleteEbsRequest{}
+	for _, id := range ebsUUIDs {
+		req.Ebs = append(req.Ebs, &compute.DeleteEbsRequest_Input{EbsUuid: id})
+	}
+	resp, e := t.cli.DeleteEbs(ctx, req)
+	if e != nil {
+		return nil, fmt.Errorf("delete ebs error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return nil, newAPIError("delete ebs", resp.Error)
+	}
+
+	jobs := t.waitForJobs(ctx, ebsUUIDs, resp.Data)
+	for _, id := range ebsUUIDs {
+		if jobs[id].waitErr != nil || jobs[id].job != nil {
+			results[id] = jobs[id].err("delete ebs")
+			continue
+		}
+		_, e := t.Get(ctx, id)
+		switch {
+		case errors.Is(e, NotFound):
+			results[id] = nil
+		case e != nil:
+			results[id] = e
+		default:
+			results[id] = jobs[id].failed("delete ebs", id)
+		}
+	}
+	return results, nil
+}
+
+// AttachMany attaches each ebs to its dc2 in one request, returns the device or error of each ebs
+func (t *ebsClient) AttachMany(ctx context.Context, attachments map[string]Dc2Ref) (map[string]*AttachResult, error) {
+	klog.V(4).Infof("attaching %d ebs", len(attachments))
+	results := make(map[string]*AttachResult, len(attachments))
+	dc2UUIDs := make(map[string]string, len(attachments))
+	req := &compute.AttachEbsRequest{}
+	var ebsUUIDs []string
+	for id, dc2 := range attachments {
+		dc2UUID, e := t.getDc2UUID(ctx, dc2)
+		if e != nil {
+			results[id] = &AttachResult{Err: e}
+			continue
+		}
+		dc2UUIDs[id] = dc2UUID
+		ebsUUIDs = append(ebsUUIDs, id)
+		req.Ebs = append(req.Ebs, &compute.AttachEbsRequest_Input{EbsUuid: id, Dc2Uuid: dc2UUID})
+	}
+	if len(req.Ebs) == 0 {
+		return results, nil
+	}
+	resp, e := t.cli.AttachEbs(ctx, req)
+	if e != nil {
+		return nil, fmt.Errorf("attach ebs error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return nil, newAPIError("attach ebs", resp.Error)
+	}
+
+	jobs := t.waitForJobs(ctx, ebsUUIDs, resp.Data)
+	for _, id := range ebsUUIDs {
+		if jobs[id].waitErr != nil {
+			results[id] = &AttachResult{Err: jobs[id].waitErr}
+			continue
+		}
+		// whether job.Success or not, need check attached device
+		device, e := t.attachedDevice(ctx, id, dc2UUIDs[id])
+		if e == nil && device == "" {
+			e = jobs[id].failed("attach ebs", id)
+		}
+		results[id] = &AttachResult{Device: device, Err: e}
+	}
+	return results, nil
+}
+
+// DetachMany detaches the ebs in one request, returns the error of each ebs, nil for detached ones
+func (t *ebsClient) DetachMany(ctx context.Context, ebsUUIDs []string) (map[string]error, error) {
+	ebsUUIDs = uniqueStrings(ebsUUIDs)
+	klog.V(4).Infof("detaching %d ebs", len(ebsUUIDs))
+	results := make(map[string]error, len(ebsUUIDs))
+	if len(ebsUUIDs) == 0 {
+		return results, nil
+	}
+	req := &compute.DetachEbsRequest{}
+	for _, id := range ebsUUIDs {
+		req.Ebs = append(req.Ebs, &compute.DetachEbsRequest_Input{EbsUuid: id})
+	}
+	resp, e := t.cli.DetachEbs(ctx, req)
+	if e != nil {
+		return nil, fmt.Errorf("detach ebs error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return nil, newAPIError("detach ebs", resp.Error)
+	}
+
+	jobs := t.waitForJobs(ctx, ebsUUIDs, resp.Data)
+	for _, id := range ebsUUIDs {
+		if jobs[id].waitErr != nil {
+			results[id] = jobs[id].waitErr
+			continue
+		}
+		device, e := t.attachedDevice(ctx, id, "")
+		if e == nil && device != "" {
+			e = jobs[id].failed("detach ebs", id)
+		}
+		results[id] = e
+	}
+	return results, nil
+}
+
+// ExpandMany expands each ebs to its size in GB in one request, returns the error of each ebs
+func (t *ebsClient) ExpandMany(ctx context.Context, sizes map[string]int64) (map[string]error, error) {
+	klog.V(4).Infof("expanding %d ebs", len(sizes))
+	results := make(map[string]error, len(sizes))
+	req := &compute.ChangeEbsSizeRequest{}
+	var ebsUUIDs []string
+	for id, sizeGB := range sizes {
+		// get to check size
+		info, e := t.Get(ctx, id)
+		if e != nil {
+			results[id] = e
+			continue
+		}
+		if ok, e := needExpand(info, sizeGB); !ok {
+			results[id] = e
+			continue
+		}
+		ebsUUIDs = append(ebsUUIDs, id)
+		req.Ebs = append(req.Ebs, &compute.ChangeEbsSizeRequest_Input{EbsUuid: id, Size: sizeGB})
+	}
+	if len(req.Ebs) == 0 {
+		return results, nil
+	}
+	resp, e := t.cli.ChangeEbsSize(ctx, req)
+	if e != nil {
+		return nil, fmt.Errorf("expand ebs error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return nil, newAPIError("expand ebs", resp.Error)
+	}
+
+	jobs := t.waitForJobs(ctx, ebsUUIDs, resp.Data)
+	for _, id := range ebsUUIDs {
+		if jobs[id].waitErr != nil || jobs[id].job != nil {
+			results[id] = jobs[id].err("expand ebs")
+			continue
+		}
+		info, e := t.Get(ctx, id)
+		if e == nil && info.GetSize() < sizes[id]<<30 {
+			e = jobs[id].failed("expand ebs", id)
+		}
+		results[id] = e
+	}
+	return results, nil
+}
+
+// batchJob is the job of one ebs in a batch request, job is nil if no job of the ebs is found in the response
+type batchJob struct {
+	job     *base.JobInfo
+	waitErr error
+}
+
+// err returns the error of waiting for the job, or of the job itself
+func (t *batchJob) err(op string) error {
+	if t.waitErr != nil {
+		return t.waitErr
+	}
+	if !t.job.Success {
+		return newJobError(op, t.job)
+	}
+	return nil
+}
+
+// failed returns the error of an ebs not reaching the state expected
+func (t *batchJob) failed(op, ebsUUID string) error {
+	if t.waitErr != nil {
+		return t.waitErr
+	}
+	if t.job != nil {
+		return newJobError(op, t.job)
+	}
+	return fmt.Errorf("failed to %s %s, no job of it found", op, ebsUUID)
+}
+
+// waitForJobs waits for all jobs of a batch request concurrently, and matches them to ebs by their resource uuid.
+// The api may not return one job for each ebs, so ebs without a job should be checked by their state
+func (t *ebsClient) waitForJobs(ctx context.Context, ebsUUIDs []string, jobs []*base.JobInfo) map[string]*batchJob {
+	if len(jobs) != len(ebsUUIDs) {
+		klog.Warningf("got %d jobs for %d ebs", len(jobs), len(ebsUUIDs))
+	}
+	done := make([]batchJob, len(jobs))
+	var wg sync.WaitGroup
+	for i, info := range jobs {
+		wg.Add(1)
+		go func(r *batchJob, info *base.JobInfo) {
+			defer wg.Done()
+			r.job, r.waitErr = t.waitForJob(ctx, info, "", "")
+		}(&done[i], info)
+	}
+	wg.Wait()
+
+	results := make(map[string]*batchJob, len(ebsUUIDs))
+	for _, id := range ebsUUIDs {
+		results[id] = &batchJob{}
+	}
+	for i, info := range jobs {
+		id := info.GetResourceUuid()
+		if id == "" {
+			id = done[i].job.GetResourceUuid()
+		}
+		if r, ok := results[id]; ok && r.job == nil && r.waitErr == nil {
+			*r = done[i]
+		}
+	}
+	return results
+}
+
+func uniqueStrings(ss []string) []string {
+	seen := make(map[string]bool, len(ss))
+	var unique []string
+	for _, s := range ss {
+		if !seen[s] {
+			seen[s] = true
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
@@ -2,89 +2,351 @@ package pkg
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
 	"github.com/didiyun/didiyun-go-sdk/compute/v1"
 	"github.com/pborman/uuid"
 )
 
 type ebsInfo struct {
-	id string
-	// dc2Name string
-	dc2Ip string
+	id       string
+	regionID string
+	zoneID   string
+	dc2      *compute.Dc2Info // attached to
+	typ      EbsType
+	sizeGB   int64
+	tags     []string
 }
 
 type mockEbsClient struct {
-	ebs map[string]*ebsInfo
+	ebs    map[string]*ebsInfo
+	snaps  map[string]*compute.SnapInfo
+	client *mockClient
 }
 
 var _ EbsClient = (*mockEbsClient)(nil)
 
-func (t *mockEbsClient) Create(ctx context.Context, regionID, zoneID, name, typ string, sizeGB int64) (string, error) {
+func (t *mockEbsClient) Create(ctx context.Context, regionID, zoneID, name string, typ EbsType, sizeGB int64) (string, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return "", e
+	}
+	if e := typ.validate(sizeGB); e != nil {
+		return "", e
+	}
 	_, ok := t.ebs[name]
 	if ok {
 		return "", fmt.Errorf("%s already exist", name)
 	}
 	id := uuid.NewUUID().String()
-	t.ebs[name] = &ebsInfo{id: id}
+	t.ebs[name] = &ebsInfo{id: id, regionID: regionID, zoneID: zoneID, typ: typ, sizeGB: sizeGB}
+	return id, nil
+}
+
+func (t *mockEbsClient) CreateWithOptions(ctx context.Context, regionID, zoneID string, opts *CreateEbsOptions) (string, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return "", e
+	}
+	if opts == nil {
+		return "", fmt.Errorf("nil ebs options %w", InvalidArgument)
+	}
+	if e := opts.Type.validate(opts.SizeGB); e != nil {
+		return "", e
+	}
+	if opts.SnapUuid != "" {
+		if _, ok := t.snaps[opts.SnapUuid]; !ok {
+			return "", fmt.Errorf("snapshot %s %w", opts.SnapUuid, NotFound)
+		}
+	}
+	if opts.Dc2 != nil {
+		if _, e := t.client.dc2.find(*opts.Dc2); e != nil {
+			return "", e
+		}
+	}
+	id, e := t.Create(ctx, regionID, zoneID, opts.Name, opts.Type, opts.SizeGB)
+	if e != nil {
+		return "", e
+	}
+	t.ebs[opts.Name].tags = opts.Tags
+	if opts.Dc2 != nil {
+		if _, e = t.AttachTo(ctx, id, *opts.Dc2); e != nil {
+			return "", e
+		}
+	}
+	return id, nil
+}
+
+func (t *mockEbsClient) CreateOrGet(ctx context.Context, regionID, zoneID, name string, typ EbsType, sizeGB int64, token string) (string, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return "", e
+	}
+	if e := typ.validate(sizeGB); e != nil {
+		return "", e
+	}
+	var existing []*ebsInfo
+	for n, info := range t.ebs {
+		if token != "" && hasTag(info.tags, clientTokenTag(token)) || token == "" && n == name {
+			existing = append(existing, info)
+		}
+	}
+	for _, info := range existing {
+		if strings.EqualFold(string(info.typ), string(typ)) && info.sizeGB == sizeGB {
+			return info.id, nil
+		}
+	}
+	if len(existing) > 0 {
+		info := existing[0]
+		return "", fmt.Errorf("ebs %s exists as %s, type %s, size %d GiB: %w", name, info.id, info.typ, info.sizeGB, AlreadyExists)
+	}
+	id, e := t.Create(ctx, regionID, zoneID, name, typ, sizeGB)
+	if e != nil {
+		return "", e
+	}
+	if token != "" {
+		t.ebs[name].tags = []string{clientTokenTag(token)}
+	}
 	return id, nil
 }
 
 func (t *mockEbsClient) Get(ctx context.Context, ebsUUID string) (*compute.EbsInfo, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
 	for name, info := range t.ebs {
 		if info.id == ebsUUID {
-			ebs := &compute.EbsInfo{
-				Name:    name,
-				EbsUuid: ebsUUID,
-			}
-			if info.dc2Ip != "" {
-				ebs.Dc2 = &compute.Dc2Info{Ip: info.dc2Ip}
-			}
-			return ebs, nil
+			return info.toEbsInfo(name), nil
 		}
 	}
 
-	return nil, fmt.Errorf("ebs %s not found", ebsUUID)
+	return nil, fmt.Errorf("ebs %s %w", ebsUUID, NotFound)
+}
+
+func (t *mockEbsClient) List(ctx context.Context, filter *EbsFilter) ([]*compute.EbsInfo, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	if filter == nil || filter.RegionID == "" {
+		return nil, fmt.Errorf("list ebs requires region %w", InvalidArgument)
+	}
+	var list []*compute.EbsInfo
+	for name, info := range t.ebs {
+		if info.regionID != filter.RegionID || filter.ZoneID != "" && info.zoneID != filter.ZoneID {
+			continue
+		}
+		ebs := info.toEbsInfo(name)
+		if filter.Dc2Uuid != "" && ebs.GetDc2().GetDc2Uuid() != filter.Dc2Uuid || !filter.match(ebs) {
+			continue
+		}
+		list = append(list, ebs)
+	}
+	return list, nil
+}
+
+func (t *ebsInfo) toEbsInfo(name string) *compute.EbsInfo {
+	return &compute.EbsInfo{
+		Name:    name,
+		EbsUuid: t.id,
+		Type:    string(t.typ),
+		Size:    t.sizeGB << 30,
+		EbsTags: t.tags,
+		Region:  &base.RegionAndZoneInfo{Id: t.regionID, Zone: &base.ZoneInfo{Id: t.zoneID}},
+		Dc2:     t.dc2,
+	}
 }
 
 func (t *mockEbsClient) Delete(ctx context.Context, ebsUUID string) error {
+	if e := t.client.checkClosed(); e != nil {
+		return e
+	}
 	for n, e := range t.ebs {
 		if ebsUUID == e.id {
 			delete(t.ebs, n)
 			return nil
 		}
 	}
-	return fmt.Errorf("%s not found", ebsUUID)
+	return fmt.Errorf("ebs %s %w", ebsUUID, NotFound)
 }
 
 func (t *mockEbsClient) Attach(ctx context.Context, ebsUUID, dc2Ip string) (string, error) {
+	return t.AttachTo(ctx, ebsUUID, Dc2Ref{Ip: dc2Ip})
+}
+
+func (t *mockEbsClient) AttachTo(ctx context.Context, ebsUUID string, dc2 Dc2Ref) (string, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return "", e
+	}
+	d, err := t.client.dc2.find(dc2)
+	if err != nil {
+		return "", err
+	}
 	for _, e := range t.ebs {
 		if ebsUUID == e.id {
-			if e.dc2Ip != "" && e.dc2Ip != dc2Ip {
-				return "", fmt.Errorf("%s attached to %s", ebsUUID, e.dc2Ip)
+			if e.dc2 != nil && e.dc2 != d {
+				return "", fmt.Errorf("%s attached to %s", ebsUUID, e.dc2.Dc2Uuid)
 			}
-			e.dc2Ip = dc2Ip
+			e.dc2 = d
 			return "mock-device", nil
 		}
 	}
-	return "", fmt.Errorf("%s not found", ebsUUID)
+	return "", fmt.Errorf("ebs %s %w", ebsUUID, NotFound)
 }
 
 func (t *mockEbsClient) Detach(ctx context.Context, ebsUUID string) error {
+	if e := t.client.checkClosed(); e != nil {
+		return e
+	}
 	for _, e := range t.ebs {
 		if ebsUUID == e.id {
-			e.dc2Ip = ""
+			e.dc2 = nil
 			return nil
 		}
 	}
-	return fmt.Errorf("%s not found", ebsUUID)
+	return fmt.Errorf("ebs %s %w", ebsUUID, NotFound)
+}
+
+func (t *mockEbsClient) DetachFrom(ctx context.Context, ebsUUID string, dc2 Dc2Ref) error {
+	if e := t.client.checkClosed(); e != nil {
+		return e
+	}
+	d, err := t.client.dc2.find(dc2)
+	if err != nil {
+		return err
+	}
+	for _, e := range t.ebs {
+		if ebsUUID == e.id {
+			if e.dc2 != nil && e.dc2.GetDc2Uuid() != d.GetDc2Uuid() {
+				return fmt.Errorf("ebs %s is attached to dc2 %s, not %s %w", ebsUUID, e.dc2.GetDc2Uuid(), dc2, Conflict)
+			}
+			e.dc2 = nil
+			return nil
+		}
+	}
+	return fmt.Errorf("ebs %s %w", ebsUUID, NotFound)
 }
 
 func (t *mockEbsClient) Expand(ctx context.Context, ebsUUID string, sizeGB int64) error {
+	if e := t.client.checkClosed(); e != nil {
+		return e
+	}
 	for _, e := range t.ebs {
 		if ebsUUID == e.id {
+			if sizeGB < e.sizeGB {
+				return fmt.Errorf("can not shrink size from %d %w", e.sizeGB<<30, InvalidArgument)
+			}
+			if err := e.typ.validate(sizeGB); err != nil {
+				return err
+			}
+			e.sizeGB = sizeGB
 			return nil
 		}
 	}
-	return fmt.Errorf("%s not found", ebsUUID)
+	return fmt.Errorf("ebs %s %w", ebsUUID, NotFound)
+}
+
+func (t *mockEbsClient) DeleteMany(ctx context.Context, ebsUUIDs []string) (map[string]error, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	results := make(map[string]error, len(ebsUUIDs))
+	for _, id := range uniqueStrings(ebsUUIDs) {
+		results[id] = t.Delete(ctx, id)
+	}
+	return results, nil
+}
+
+func (t *mockEbsClient) AttachMany(ctx context.Context, attachments map[string]Dc2Ref) (map[string]*AttachResult, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	results := make(map[string]*AttachResult, len(attachments))
+	for id, dc2 := range attachments {
+		device, e := t.AttachTo(ctx, id, dc2)
+		results[id] = &AttachResult{Device: device, Err: e}
+	}
+	return results, nil
+}
+
+func (t *mockEbsClient) DetachMany(ctx context.Context, ebsUUIDs []string) (map[string]error, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	results := make(map[string]error, len(ebsUUIDs))
+	for _, id := range uniqueStrings(ebsUUIDs) {
+		results[id] = t.Detach(ctx, id)
+	}
+	return results, nil
+}
+
+func (t *mockEbsClient) ExpandMany(ctx context.Context, sizes map[string]int64) (map[string]error, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	results := make(map[string]error, len(sizes))
+	for id, sizeGB := range sizes {
+		results[id] = t.Expand(ctx, id, sizeGB)
+	}
+	return results, nil
+}
+
+func (t *mockEbsClient) WaitFor(ctx context.Context, ebsUUID string, cond EbsPredicate) (*compute.EbsInfo, error) {
+	info, e := t.Get(ctx, ebsUUID)
+	if e != nil {
+		return nil, e
+	}
+	ok, e := cond(info)
+	if e != nil || ok {
+		return info, e
+	}
+	// nothing changes a mock ebs in the background
+	<-ctx.Done()
+	return info, fmt.Errorf("wait for ebs %s error %w", ebsUUID, ctx.Err())
+}
+
+func (t *mockEbsClient) CreateSnapshot(ctx context.Context, ebsUUID, name string) (string, error) {
+	ebs, e := t.Get(ctx, ebsUUID)
+	if e != nil {
+		return "", e
+	}
+	id := uuid.NewUUID().String()
+	t.snaps[id] = &compute.SnapInfo{SnapUuid: id, Name: name, Ebs: ebs}
+	return id, nil
+}
+
+func (t *mockEbsClient) ListSnapshots(ctx context.Context, ebsUUID string) ([]*compute.SnapInfo, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	if ebsUUID == "" {
+		return nil, errors.New("empty ebs uuid")
+	}
+	var snaps []*compute.SnapInfo
+	for _, s := range t.snaps {
+		if s.Ebs.EbsUuid == ebsUUID {
+			snaps = append(snaps, s)
+		}
+	}
+	return snaps, nil
+}
+
+func (t *mockEbsClient) DeleteSnapshot(ctx context.Context, snapUUID string) error {
+	if e := t.client.checkClosed(); e != nil {
+		return e
+	}
+	if _, ok := t.snaps[snapUUID]; !ok {
+		return fmt.Errorf("snapshot %s %w", snapUUID, NotFound)
+	}
+	delete(t.snaps, snapUUID)
+	return nil
+}
+
+func (t *mockEbsClient) CreateFromSnapshot(ctx context.Context, regionID, zoneID, name, snapUUID string, typ EbsType, sizeGB int64) (string, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return "", e
+	}
+	if _, ok := t.snaps[snapUUID]; !ok {
+		return "", fmt.Errorf("snapshot %s %w", snapUUID, NotFound)
+	}
+	return t.Create(ctx, regionID, zoneID, name, typ, sizeGB)
 }
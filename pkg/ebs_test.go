@@ -0,0 +1,338 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"google.golang.org/grpc"
+)
+
+// fakeEbsClient pages ListEbs over ebs, and records created ebs with jobs done at once
+type fakeEbsClient struct {
+	compute.EbsClient
+	ebs     []*compute.EbsInfo
+	created []*compute.CreateEbsRequest
+	// afterDetach is called once an ebs is detached, eg. to attach it to another dc2
+	afterDetach func(*compute.EbsInfo)
+	// oneJob responds a batch request with one job without resource uuid, instead of one job for each ebs
+	oneJob bool
+	// snaps could be restored from, creating from others fails as not found
+	snaps map[string]bool
+}
+
+func (t *fakeEbsClient) GetEbsByUuid(ctx context.Context, req *compute.GetEbsByUuidRequest, opts ...grpc.CallOption) (*compute.GetEbsByUuidResponse, error) {
+	resp := &compute.GetEbsByUuidResponse{Error: &base.Error{}}
+	if info := t.find(req.EbsUuid); info != nil {
+		resp.Data = []*compute.EbsInfo{info}
+	}
+	return resp, nil
+}
+
+func (t *fakeEbsClient) DetachEbs(ctx context.Context, req *compute.DetachEbsRequest, opts ...grpc.CallOption) (*compute.DetachEbsResponse, error) {
+	resp := &compute.DetachEbsResponse{Error: &base.Error{}}
+	for _, in := range req.Ebs {
+		job := &base.JobInfo{ResourceUuid: in.EbsUuid, Done: true}
+		if info := t.find(in.EbsUuid); info != nil {
+			info.Dc2 = nil
+			job.Success = true
+			if t.afterDetach != nil {
+				t.afterDetach(info)
+			}
+		} else {
+			job.Result = ebsNotFoundMsg
+		}
+		resp.Data = append(resp.Data, job)
+	}
+	resp.Data = t.batchJobs(resp.Data)
+	return resp, nil
+}
+
+func (t *fakeEbsClient) DeleteEbs(ctx context.Context, req *compute.DeleteEbsRequest, opts ...grpc.CallOption) (*compute.DeleteEbsResponse, error) {
+	resp := &compute.DeleteEbsResponse{Error: &base.Error{}}
+	for _, in := range req.Ebs {
+		job := &base.JobInfo{ResourceUuid: in.EbsUuid, Done: true, Result: ebsNotFoundMsg}
+		for i, info := range t.ebs {
+			if info.EbsUuid == in.EbsUuid {
+				t.ebs = append(t.ebs[:i], t.ebs[i+1:]...)
+				job.Success, job.Result = true, ""
+				break
+			}
+		}
+		resp.Data = append(resp.Data, job)
+	}
+	resp.Data = t.batchJobs(resp.Data)
+	return resp, nil
+}
+
+// batchJobs returns jobs as is, or a single job of them all if oneJob
+func (t *fakeEbsClient) batchJobs(jobs []*base.JobInfo) []*base.JobInfo {
+	if !t.oneJob {
+		return jobs
+	}
+	batch := &base.JobInfo{Done: true, Success: true}
+	for _, j := range jobs {
+		batch.Success = batch.Success && j.Success
+	}
+	return []*base.JobInfo{batch}
+}
+
+func (t *fakeEbsClient) find(ebsUUID string) *compute.EbsInfo {
+	for _, info := range t.ebs {
+		if info.EbsUuid == ebsUUID {
+			return info
+		}
+	}
+	return nil
+}
+
+func (t *fakeEbsClient) ListEbs(ctx context.Context, req *compute.ListEbsRequest, opts ...grpc.CallOption) (*compute.ListEbsResponse, error) {
+	resp := &compute.ListEbsResponse{Error: &base.Error{}}
+	for i := req.Start; i < req.Start+req.Limit && int(i) < len(t.ebs); i++ {
+		resp.Data = append(resp.Data, t.ebs[i])
+	}
+	return resp, nil
+}
+
+func (t *fakeEbsClient) CreateEbs(ctx context.Context, req *compute.CreateEbsRequest, opts ...grpc.CallOption) (*compute.CreateEbsResponse, error) {
+	t.created = append(t.created, req)
+	if req.SnapUuid != "" && !t.snaps[req.SnapUuid] {
+		return &compute.CreateEbsResponse{
+			Error: &base.Error{},
+			Data:  []*base.JobInfo{{Done: true, Result: snapNotFoundMsg}},
+		}, nil
+	}
+	return &compute.CreateEbsResponse{
+		Error: &base.Error{},
+		Data:  []*base.JobInfo{{Done: true, Success: true, ResourceUuid: "created"}},
+	}, nil
+}
+
+func newFakeEbsClient(fake *fakeEbsClient) *ebsClient {
+	return &ebsClient{cli: fake, helper: &client{poll: PollPolicy{}.withDefaults()}}
+}
+
+func TestCreateOrGet(t *testing.T) {
+	tagged := func(uuid, typ string, sizeGB int64, token string) *compute.EbsInfo {
+		return &compute.EbsInfo{EbsUuid: uuid, Name: "data", Type: typ, Size: sizeGB << 30, EbsTags: []string{clientTokenTag(token)}}
+	}
+	ctx := context.Background()
+
+	// the matching one is returned even if listed after a mismatching one
+	fake := &fakeEbsClient{ebs: []*compute.EbsInfo{
+		tagged("ebs-small", "SSD", 20, "token-1"),
+		tagged("ebs-match", "ssd", 40, "token-1"),
+	}}
+	id, e := newFakeEbsClient(fake).CreateOrGet(ctx, "gz", "gz02", "data", EbsTypeSSD, 40, "token-1")
+	if e != nil || id != "ebs-match" {
+		t.Errorf("expect ebs-match, got %s, error %v", id, e)
+	}
+
+	if _, e := newFakeEbsClient(fake).CreateOrGet(ctx, "gz", "gz02", "data", EbsTypeSSD, 60, "token-1"); !errors.Is(e, AlreadyExists) {
+		t.Errorf("expect AlreadyExists, got %v", e)
+	}
+
+	// matched by name without token
+	if id, e := newFakeEbsClient(fake).CreateOrGet(ctx, "gz", "gz02", "data", EbsTypeSSD, 20, ""); e != nil || id != "ebs-small" {
+		t.Errorf("expect ebs-small, got %s, error %v", id, e)
+	}
+	if len(fake.created) != 0 {
+		t.Errorf("expect nothing created, got %d", len(fake.created))
+	}
+
+	// created with the token tag if nothing matches
+	fake = &fakeEbsClient{ebs: make([]*compute.EbsInfo, 0, maxEbs+1)}
+	for i := 0; i < maxEbs; i++ {
+		fake.ebs = append(fake.ebs, tagged("other", "SSD", 20, "token-2"))
+	}
+	id, e = newFakeEbsClient(fake).CreateOrGet(ctx, "gz", "gz02", "data", EbsTypeSSD, 40, "token-1")
+	if e != nil || id != "created" {
+		t.Fatalf("expect created, got %s, error %v", id, e)
+	}
+	if tags := fake.created[0].Tags; len(tags) != 1 || tags[0] != clientTokenTag("token-1") {
+		t.Errorf("expect tagged by token, got %v", tags)
+	}
+}
+
+func TestCreateInvalid(t *testing.T) {
+	fake := &fakeEbsClient{ebs: []*compute.EbsInfo{{EbsUuid: "ebs-1", Name: "data", Type: "ssd", Size: 20 << 30}}}
+	ebs := newFakeEbsClient(fake) // without a dc2 client, resolving a dc2 panics
+	ctx := context.Background()
+
+	// rejected before listing, rather than returning the existing one of the same type in lower case
+	if id, e := ebs.CreateOrGet(ctx, "gz", "gz02", "data", "ssd", 20, ""); !errors.Is(e, InvalidArgument) {
+		t.Errorf("expect InvalidArgument, got %s, error %v", id, e)
+	}
+	// rejected before resolving dc2
+	opts := &CreateEbsOptions{Name: "data", Type: EbsTypeSSD, SizeGB: 1, Dc2: &Dc2Ref{Name: "node-1"}}
+	if _, e := ebs.CreateWithOptions(ctx, "gz", "gz02", opts); !errors.Is(e, InvalidArgument) {
+		t.Errorf("expect InvalidArgument, got %v", e)
+	}
+	if len(fake.created) != 0 {
+		t.Errorf("expect nothing created, got %d", len(fake.created))
+	}
+}
+
+func TestDetachFrom(t *testing.T) {
+	// refs are resolved by listing dc2, not compared with the dc2 nested in the ebs
+	node1 := &compute.Dc2Info{Dc2Uuid: "dc2-1"}
+	node2 := &compute.Dc2Info{Dc2Uuid: "dc2-2"}
+	fake := &fakeEbsClient{ebs: []*compute.EbsInfo{{EbsUuid: "ebs-1", Dc2: node1}}}
+	ebs := newFakeEbsClient(fake)
+	ebs.helper.(*client).dc2 = &fakeDc2Client{dc2s: []*compute.Dc2Info{
+		{Dc2Uuid: "dc2-1", Name: "node-1", Ip: "10.0.0.1"},
+		{Dc2Uuid: "dc2-2", Name: "node-2", Ip: "10.0.0.2"},
+	}}
+	ctx := context.Background()
+
+	if e := ebs.DetachFrom(ctx, "ebs-1", Dc2Ref{Name: "node-2"}); !errors.Is(e, Conflict) {
+		t.Errorf("expect Conflict, got %v", e)
+	}
+	if e := ebs.DetachFrom(ctx, "ebs-1", Dc2Ref{Ip: "10.0.0.1"}); e != nil {
+		t.Errorf("expect detached, got %v", e)
+	}
+	if e := ebs.DetachFrom(ctx, "ebs-1", Dc2Ref{Ip: "10.0.0.1"}); e != nil {
+		t.Errorf("expect already detached ok, got %v", e)
+	}
+
+	// moved to another dc2 while detaching
+	fake.ebs[0].Dc2 = node1
+	fake.afterDetach = func(info *compute.EbsInfo) { info.Dc2 = node2 }
+	if e := ebs.DetachFrom(ctx, "ebs-1", Dc2Ref{Uuid: "dc2-1"}); !errors.Is(e, Conflict) {
+		t.Errorf("expect Conflict, got %v", e)
+	}
+
+	if e := ebs.DetachFrom(ctx, "no-such-ebs", Dc2Ref{Uuid: "dc2-1"}); !errors.Is(e, NotFound) {
+		t.Errorf("expect NotFound, got %v", e)
+	}
+	if e := ebs.DetachFrom(ctx, "ebs-1", Dc2Ref{Name: "no-such-dc2"}); !errors.Is(e, NotFound) {
+		t.Errorf("expect NotFound, got %v", e)
+	}
+}
+
+func TestEbsSizeLimits(t *testing.T) {
+	for typ, l := range ebsSizeLimits {
+		if l.MinGB%l.StepGB != 0 || l.MaxGB%l.StepGB != 0 || l.MinGB > l.MaxGB {
+			t.Errorf("limits of %s not aligned to step: %+v", typ, l)
+		}
+		if e := typ.validate(l.MinGB); e != nil {
+			t.Errorf("min size of %s: %v", typ, e)
+		}
+		if e := typ.validate(l.MaxGB); e != nil {
+			t.Errorf("max size of %s: %v", typ, e)
+		}
+		for _, size := range []int64{l.MinGB - l.StepGB, l.MaxGB + l.StepGB, l.MinGB + 1} {
+			if e := typ.validate(size); !errors.Is(e, InvalidArgument) {
+				t.Errorf("size %d of %s: expect InvalidArgument, got %v", size, typ, e)
+			}
+		}
+	}
+}
+
+func TestNeedExpand(t *testing.T) {
+	cases := []struct {
+		typ    string
+		cur    int64 // GB
+		size   int64 // GB
+		expand bool
+		err    error
+	}{
+		{"SSD", 20, 20, false, nil},
+		{"ssd", 20, 40, true, nil},
+		{"HE", 40, 20, false, InvalidArgument},
+		{"SSD", 20, 25, false, InvalidArgument},
+		{"SSD", 20, 16390, false, InvalidArgument},
+		{"HDD", 20, 40, false, InvalidArgument},
+	}
+	for _, c := range cases {
+		ok, e := needExpand(&compute.EbsInfo{Type: c.typ, Size: c.cur << 30}, c.size)
+		if ok != c.expand || c.err == nil && e != nil || c.err != nil && !errors.Is(e, c.err) {
+			t.Errorf("expand %s from %d to %d GB, got %v, %v", c.typ, c.cur, c.size, ok, e)
+		}
+	}
+}
+
+func TestCreateDefaults(t *testing.T) {
+	fake := &fakeEbsClient{snaps: map[string]bool{"snap-1": true}}
+	ebs := newFakeEbsClient(fake)
+	ctx := context.Background()
+
+	if _, e := ebs.CreateWithOptions(ctx, "gz", "gz02", nil); !errors.Is(e, InvalidArgument) {
+		t.Errorf("expect InvalidArgument of nil options, got %v", e)
+	}
+	if _, e := ebs.Create(ctx, "gz", "gz02", "data", EbsTypeSSD, 20); e != nil {
+		t.Fatal(e)
+	}
+	if _, e := ebs.CreateFromSnapshot(ctx, "gz", "gz02", "restored", "snap-1", EbsTypeHE, 40); e != nil {
+		t.Fatal(e)
+	}
+	if _, e := ebs.CreateOrGet(ctx, "gz", "gz02", "tokened", EbsTypeSSD, 20, "token-1"); e != nil {
+		t.Fatal(e)
+	}
+
+	for _, req := range fake.created {
+		if req.Count != 1 || req.Header.GetRegionId() != "gz" || req.Header.GetZoneId() != "gz02" || req.PayPeriod != 0 || req.AutoContinue {
+			t.Errorf("unexpected defaults of %s: %+v", req.Name, req)
+		}
+	}
+	if len(fake.created) != 3 || fake.created[1].SnapUuid != "snap-1" || fake.created[1].DiskType != "HE" || fake.created[1].Size != 40 {
+		t.Errorf("unexpected requests %+v", fake.created)
+	}
+}
+
+func TestBatchJobsMatched(t *testing.T) {
+	for _, oneJob := range []bool{false, true} {
+		fake := &fakeEbsClient{oneJob: oneJob}
+		for _, id := range []string{"ebs-1", "ebs-2", "ebs-3"} {
+			fake.ebs = append(fake.ebs, &compute.EbsInfo{EbsUuid: id, Dc2: &compute.Dc2Info{Dc2Uuid: "dc2-1"}})
+		}
+		ebs := newFakeEbsClient(fake)
+		ctx := context.Background()
+
+		// duplicates in the input do not mismatch jobs
+		detached, e := ebs.DetachMany(ctx, []string{"ebs-1", "ebs-2", "ebs-1", "no-such-ebs"})
+		if e != nil {
+			t.Fatal(e)
+		}
+		if len(detached) != 3 || detached["ebs-1"] != nil || detached["ebs-2"] != nil || !errors.Is(detached["no-such-ebs"], NotFound) {
+			t.Errorf("one job %v: unexpected detach results %v", oneJob, detached)
+		}
+
+		deleted, e := ebs.DeleteMany(ctx, []string{"ebs-3", "ebs-2", "ebs-3"})
+		if e != nil {
+			t.Fatal(e)
+		}
+		if len(deleted) != 2 || deleted["ebs-2"] != nil || deleted["ebs-3"] != nil {
+			t.Errorf("one job %v: unexpected delete results %v", oneJob, deleted)
+		}
+		if len(fake.ebs) != 1 || fake.ebs[0].EbsUuid != "ebs-1" {
+			t.Errorf("one job %v: unexpected ebs left %v", oneJob, fake.ebs)
+		}
+	}
+}
+
+func TestWaitForFailedJob(t *testing.T) {
+	failed := &base.JobInfo{Type: "AttachEbs", Done: true, Result: "attach failed"}
+	fake := &fakeEbsClient{ebs: []*compute.EbsInfo{{EbsUuid: "ebs-1", Job: failed}}}
+	ebs := &ebsClient{cli: fake, helper: &client{poll: PollPolicy{InitialInterval: time.Millisecond, Timeout: time.Second}.withDefaults()}}
+	ctx := context.Background()
+
+	_, e := ebs.WaitFor(ctx, "ebs-1", EbsAttachedTo("dc2-1"))
+	var ae *APIError
+	if !errors.As(e, &ae) || ae.Job != failed {
+		t.Errorf("expect the failed job, got %v", e)
+	}
+
+	// a failed job does not matter once the state is reached
+	if _, e := ebs.WaitFor(ctx, "ebs-1", EbsDetached); e != nil {
+		t.Errorf("expect detached, got %v", e)
+	}
+
+	fake.ebs[0].Job = &base.JobInfo{Type: "AttachEbs", Progress: 50}
+	if _, e := ebs.WaitFor(ctx, "ebs-1", EbsAttachedTo("dc2-1")); !errors.Is(e, context.DeadlineExceeded) {
+		t.Errorf("expect timeout of a job in progress, got %v", e)
+	}
+}
@@ -0,0 +1,235 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"k8s.io/klog"
+)
+
+const (
+	maxEip = 100
+)
+
+// EipBindingType is the kind of resource an eip is bound to
+type EipBindingType string
+
+const (
+	EipBindingDc2 EipBindingType = "dc2"
+	EipBindingSlb EipBindingType = "slb"
+)
+
+type EipClient interface {
+	Create(ctx context.Context, regionID, zoneID string, bandwidth int64, chargeWithFlow bool, tags []string) (string, error)
+	Get(ctx context.Context, eipUUID string) (*compute.EipInfo, error)
+	List(ctx context.Context, regionID string, filter *EipFilter) ([]*compute.EipInfo, error)
+	Bind(ctx context.Context, eipUUID string, typ EipBindingType, bindingUUID string) error
+	Unbind(ctx context.Context, eipUUID string) error
+	ChangeBandwidth(ctx context.Context, eipUUID string, bandwidth int64, chargeWithFlow bool) error
+	Delete(ctx context.Context, eipUUID string) error
+}
+
+// EipFilter selects eip to list, empty fields match all
+type EipFilter struct {
+	Ip          string
+	Uuids       []string
+	NotAttached bool
+	Dc2Uuid     string
+	Dc2Name     string
+}
+
+type eipClient struct {
+	cli compute.EipClient
+	helper
+}
+
+var _ EipClient = (*eipClient)(nil)
+
+func (t *eipClient) Create(ctx context.Context, regionID, zoneID string, bandwidth int64, chargeWithFlow bool, tags []string) (string, error) {
+	klog.V(4).Infof("creating eip, bandwidth %d Mbps", bandwidth)
+	req := &compute.CreateEipRequest{
+		Header:         &base.Header{RegionId: regionID, ZoneId: zoneID},
+		Count:          1,
+		AutoContinue:   false,
+		PayPeriod:      0,
+		Bandwidth:      int32(bandwidth), // Mbps
+		ChargeWithFlow: chargeWithFlow,
+		Tags:           tags,
+	}
+	resp, e := t.cli.CreateEip(ctx, req)
+	if e != nil {
+		return "", fmt.Errorf("create eip error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return "", newAPIError("create eip", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], regionID, zoneID)
+	if e != nil {
+		return "", e
+	}
+	if !job.Success { // uuid is returned if created anyway, so that it could be cleaned up
+		return job.ResourceUuid, newJobError("create eip", job)
+	}
+	return job.ResourceUuid, nil
+}
+
+func (t *eipClient) Get(ctx context.Context, eipUUID string) (*compute.EipInfo, error) {
+	klog.V(4).Infof("get eip %s", eipUUID)
+	resp, e := t.cli.GetEipByUuid(ctx, &compute.GetEipByUuidRequest{
+		EipUuid: eipUUID,
+	})
+	if e != nil {
+		return nil, fmt.Errorf("get eip by uuid: %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return nil, newAPIError("get eip by uuid", resp.Error)
+	}
+
+	infos := resp.GetData()
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("eip %s %w", eipUUID, NotFound)
+	}
+	if len(infos) > 1 {
+		return nil, fmt.Errorf("get eip by uuid, got too much: %v", infos)
+	}
+	return infos[0], nil
+}
+
+func (t *eipClient) List(ctx context.Context, regionID string, filter *EipFilter) ([]*compute.EipInfo, error) {
+	klog.V(4).Infof("listing eip in region %s", regionID)
+	var cond *compute.ListEipCondition
+	if filter != nil {
+		cond = &compute.ListEipCondition{
+			Eip:            filter.Ip,
+			EipUuids:       filter.Uuids,
+			EipNotAttached: filter.NotAttached,
+			Dc2Uuid:        filter.Dc2Uuid,
+			Dc2Name:        filter.Dc2Name,
+		}
+	}
+
+	var eips []*compute.EipInfo
+	e := listPages(maxEip, func(start, limit int32) (int, error) {
+		resp, e := t.cli.ListEip(ctx, &compute.ListEipRequest{
+			Header:    &base.Header{RegionId: regionID},
+			Start:     start,
+			Limit:     limit,
+			Condition: cond,
+		})
+		if e != nil {
+			return 0, fmt.Errorf("list eip error %w", e)
+		}
+		if resp.Error.Errno != 0 {
+			return 0, newAPIError("list eip", resp.Error)
+		}
+		eips = append(eips, resp.Data...)
+		return len(resp.Data), nil
+	})
+	if e != nil {
+		return nil, e
+	}
+	return eips, nil
+}
+
+func (t *eipClient) Bind(ctx context.Context, eipUUID string, typ EipBindingType, bindingUUID string) error {
+	klog.V(4).Infof("binding eip %s to %s %s", eipUUID, typ, bindingUUID)
+	req := &compute.AttachEipToDc2Request{
+		Eip: []*compute.AttachEipToDc2Request_Input{{
+			EipUuid:     eipUUID,
+			BindingUuid: bindingUUID,
+			BindingType: string(typ),
+		}},
+	}
+	resp, e := t.cli.AttachEipToDc2(ctx, req)
+	if e != nil {
+		return fmt.Errorf("bind eip error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("bind eip", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success {
+		return newJobError("bind eip", job)
+	}
+	return nil
+}
+
+func (t *eipClient) Unbind(ctx context.Context, eipUUID string) error {
+	klog.V(4).Infof("unbinding eip %s", eipUUID)
+	req := &compute.DetachEipFromDc2Request{
+		Eip: []*compute.DetachEipFromDc2Request_Input{{EipUuid: eipUUID}},
+	}
+	resp, e := t.cli.DetachEipFromDc2(ctx, req)
+	if e != nil {
+		return fmt.Errorf("unbind eip error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("unbind eip", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success {
+		return newJobError("unbind eip", job)
+	}
+	return nil
+}
+
+func (t *eipClient) ChangeBandwidth(ctx context.Context, eipUUID string, bandwidth int64, chargeWithFlow bool) error {
+	klog.V(4).Infof("changing bandwidth of eip %s to %d Mbps", eipUUID, bandwidth)
+	req := &compute.ChangeEipBandwidthRequest{
+		Eip: []*compute.ChangeEipBandwidthRequest_Input{{
+			EipUuid:        eipUUID,
+			Bandwidth:      int32(bandwidth),
+			ChargeWithFlow: chargeWithFlow,
+		}},
+	}
+	resp, e := t.cli.ChangeEipBandwidth(ctx, req)
+	if e != nil {
+		return fmt.Errorf("change eip bandwidth error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("change eip bandwidth", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success {
+		return newJobError("change eip bandwidth", job)
+	}
+	return nil
+}
+
+func (t *eipClient) Delete(ctx context.Context, eipUUID string) error {
+	klog.V(4).Infof("deleting eip %s", eipUUID)
+	req := &compute.DeleteEipRequest{
+		Eip: []*compute.DeleteEipRequest_Input{{EipUuid: eipUUID}},
+	}
+	resp, e := t.cli.DeleteEip(ctx, req)
+	if e != nil {
+		return fmt.Errorf("delete eip error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("delete eip", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success { // not found etc.
+		return newJobError("delete eip", job)
+	}
+	return nil
+}
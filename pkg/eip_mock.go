@@ -0,0 +1,90 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"github.com/pborman/uuid"
+)
+
+type mockEipClient struct {
+	eip    map[string]*compute.EipInfo
+	count  int
+	client *mockClient
+}
+
+var _ EipClient = (*mockEipClient)(nil)
+
+func (t *mockEipClient) Create(ctx context.Context, regionID, zoneID string, bandwidth int64, chargeWithFlow bool, tags []string) (string, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return "", e
+	}
+	id := uuid.NewUUID().String()
+	t.count++
+	t.eip[id] = &compute.EipInfo{
+		EipUuid: id,
+		Ip:      fmt.Sprintf("116.85.0.%d", t.count),
+		EipTags: tags,
+	}
+	return id, nil
+}
+
+func (t *mockEipClient) Get(ctx context.Context, eipUUID string) (*compute.EipInfo, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	eip, ok := t.eip[eipUUID]
+	if !ok {
+		return nil, fmt.Errorf("eip %s %w", eipUUID, NotFound)
+	}
+	return eip, nil
+}
+
+func (t *mockEipClient) List(ctx context.Context, regionID string, filter *EipFilter) ([]*compute.EipInfo, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	var eips []*compute.EipInfo
+	for _, eip := range t.eip {
+		if filter != nil && (filter.Ip != "" && eip.Ip != filter.Ip || filter.NotAttached && eip.Dc2 != nil) {
+			continue
+		}
+		eips = append(eips, eip)
+	}
+	return eips, nil
+}
+
+func (t *mockEipClient) Bind(ctx context.Context, eipUUID string, typ EipBindingType, bindingUUID string) error {
+	eip, e := t.Get(ctx, eipUUID)
+	if e != nil {
+		return e
+	}
+	if eip.Dc2 != nil && eip.Dc2.Dc2Uuid != bindingUUID {
+		return fmt.Errorf("eip %s is bound to %s", eipUUID, eip.Dc2.Dc2Uuid)
+	}
+	eip.Dc2 = &compute.Dc2Info{Dc2Uuid: bindingUUID}
+	return nil
+}
+
+func (t *mockEipClient) Unbind(ctx context.Context, eipUUID string) error {
+	eip, e := t.Get(ctx, eipUUID)
+	if e != nil {
+		return e
+	}
+	eip.Dc2 = nil
+	return nil
+}
+
+func (t *mockEipClient) ChangeBandwidth(ctx context.Context, eipUUID string, bandwidth int64, chargeWithFlow bool) error {
+	_, e := t.Get(ctx, eipUUID)
+	return e
+}
+
+func (t *mockEipClient) Delete(ctx context.Context, eipUUID string) error {
+	if _, e := t.Get(ctx, eipUUID); e != nil {
+		return e
+	}
+	delete(t.eip, eipUUID)
+	return nil
+}
@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+)
+
+const (
+	ebsNotFoundMsg  = "找不到指定EBS"
+	slbNotFoundMsg  = "找不到指定SLB"
+	snapNotFoundMsg = "找不到指定SNAP"
+	slbNotFoundCode = 41070 // 查询SLB信息失败
+)
+
+// errors could be checked by errors.Is, APIError is classified into them by its errno or message.
+// InvalidArgument is only reported by validation of this client, no api error is known to be classified into it
+var (
+	NotFound        = errors.New("not found")
+	AlreadyExists   = errors.New("already exists")
+	QuotaExceeded   = errors.New("quota exceeded")
+	InvalidArgument = errors.New("invalid argument")
+	Conflict        = errors.New("conflict")
+	Closed          = errors.New("client closed")
+)
+
+// known errnos of api responses, only for the operation, since the same errno may mean other failures elsewhere
+var errnoKinds = []struct {
+	op    string
+	errno int32
+	kind  error
+}{
+	{"get slb", slbNotFoundCode, NotFound},
+}
+
+// known phrases of api error messages and job results, checked in order.
+// NotFound is only mapped from messages naming the resource, since a job may fail for missing another resource,
+// eg. attaching an ebs to a dc2 not found must not look like the ebs is gone
+var msgKinds = []struct {
+	phrase string
+	kind   error
+}{
+	{ebsNotFoundMsg, NotFound},
+	{slbNotFoundMsg, NotFound},
+	{snapNotFoundMsg, NotFound},
+	{"已存在", AlreadyExists},
+	{"配额", QuotaExceeded},
+	{"冲突", Conflict},
+}
+
+// APIError is an error reported by didiyun api, either by a response or by the result of an async job
+type APIError struct {
+	// Op is the failed operation, eg. "create ebs"
+	Op        string
+	Errno     int32
+	Errmsg    string
+	RequestID string
+	// Job is the failed job, nil if the request itself is rejected
+	Job *base.JobInfo
+}
+
+func newAPIError(op string, e *base.Error) error {
+	return &APIError{
+		Op:        op,
+		Errno:     e.GetErrno(),
+		Errmsg:    e.GetErrmsg(),
+		RequestID: e.GetRequestId(),
+	}
+}
+
+func newJobError(op string, job *base.JobInfo) error {
+	return &APIError{
+		Op:     op,
+		Errmsg: job.GetResult(),
+		Job:    job,
+	}
+}
+
+func (e *APIError) Error() string {
+	if e.Job != nil {
+		return fmt.Sprintf("failed to %s: %s", e.Op, e.Errmsg)
+	}
+	return fmt.Sprintf("%s error %s (%d)", e.Op, e.Errmsg, e.Errno)
+}
+
+// Is reports whether e is classified as target, eg. errors.Is(e, NotFound)
+func (e *APIError) Is(target error) bool {
+	return target != nil && e.kind() == target
+}
+
+func (e *APIError) kind() error {
+	for _, k := range errnoKinds {
+		if k.op == e.Op && k.errno == e.Errno {
+			return k.kind
+		}
+	}
+	for _, m := range msgKinds {
+		if strings.Contains(e.Errmsg, m.phrase) {
+			return m.kind
+		}
+	}
+	return nil
+}
+
+// Dc2NotFoundError lists dc2 names not found in a vpc, it is classified as NotFound
+type Dc2NotFoundError struct {
+	VpcUuid string
+	Names   []string
+}
+
+func (e *Dc2NotFoundError) Error() string {
+	return fmt.Sprintf("dc2 %v not found in vpc %s", e.Names, e.VpcUuid)
+}
+
+func (e *Dc2NotFoundError) Is(target error) bool {
+	return target == NotFound
+}
@@ -0,0 +1,43 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+)
+
+func TestAPIErrorKind(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"slb errno", newAPIError("get slb", &base.Error{Errno: slbNotFoundCode, Errmsg: "查询SLB信息失败"}), NotFound},
+		{"slb errno of other op", newAPIError("delete slb", &base.Error{Errno: slbNotFoundCode, Errmsg: "查询SLB信息失败"}), nil},
+		{"ebs message", newAPIError("get ebs", &base.Error{Errno: 1, Errmsg: ebsNotFoundMsg}), NotFound},
+		{"ebs job", newJobError("delete ebs", &base.JobInfo{Done: true, Result: ebsNotFoundMsg}), NotFound},
+		{"slb job", newJobError("delete slb", &base.JobInfo{Done: true, Result: slbNotFoundMsg}), NotFound},
+		{"other resource not found", newJobError("attach ebs", &base.JobInfo{Done: true, Result: "找不到指定DC2"}), nil},
+		{"other resource not exist", newJobError("attach ebs", &base.JobInfo{Done: true, Result: "云服务器不存在"}), nil},
+		{"already exists", newAPIError("create vpc", &base.Error{Errno: 1, Errmsg: "名称已存在"}), AlreadyExists},
+		{"quota", newAPIError("create ebs", &base.Error{Errno: 1, Errmsg: "超出配额"}), QuotaExceeded},
+		{"conflict", newJobError("attach ebs", &base.JobInfo{Done: true, Result: "操作冲突"}), Conflict},
+		{"unknown", newAPIError("create ebs", &base.Error{Errno: 1, Errmsg: "内部错误"}), nil},
+		{"wrapped", fmt.Errorf("sync: %w", newAPIError("get ebs", &base.Error{Errno: 1, Errmsg: ebsNotFoundMsg})), NotFound},
+	}
+
+	kinds := []error{NotFound, AlreadyExists, QuotaExceeded, InvalidArgument, Conflict, Closed}
+	for _, c := range cases {
+		for _, k := range kinds {
+			if got := errors.Is(c.err, k); got != (k == c.kind) {
+				t.Errorf("%s: errors.Is(%v, %v) = %v", c.name, c.err, k, got)
+			}
+		}
+		var ae *APIError
+		if !errors.As(c.err, &ae) {
+			t.Errorf("%s: not an APIError", c.name)
+		}
+	}
+}
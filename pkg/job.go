@@ -0,0 +1,120 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"k8s.io/klog"
+)
+
+const (
+	jobBatchWindow  = 100 * time.Millisecond // how long a batch collects jobs before querying
+	jobBatchTimeout = 30 * time.Second
+	maxJobBatch     = 100
+)
+
+// jobWatcher merges concurrent job polls into batched JobResult calls
+type jobWatcher struct {
+	cli compute.CommonClient
+
+	mu      sync.Mutex
+	pending map[jobKey]*jobBatch
+}
+
+type jobKey struct {
+	regionID string
+	zoneID   string
+}
+
+type jobBatch struct {
+	key     jobKey
+	uuids   []string
+	flushed bool
+
+	done  chan struct{}
+	infos map[string]*base.JobInfo
+	err   error
+}
+
+func newJobWatcher(cli compute.CommonClient) *jobWatcher {
+	return &jobWatcher{
+		cli:     cli,
+		pending: make(map[jobKey]*jobBatch),
+	}
+}
+
+// result gets the current state of a job, together with other jobs polled at the same time
+func (t *jobWatcher) result(ctx context.Context, regionID, zoneID, jobUuid string) (*base.JobInfo, error) {
+	b := t.enqueue(jobKey{regionID: regionID, zoneID: zoneID}, jobUuid)
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("job result error %w", ctx.Err())
+	case <-b.done:
+	}
+
+	if b.err != nil {
+		return nil, b.err
+	}
+	info, ok := b.infos[jobUuid]
+	if !ok {
+		return nil, fmt.Errorf("job result of %s, got nothing", jobUuid)
+	}
+	return info, nil
+}
+
+func (t *jobWatcher) enqueue(key jobKey, jobUuid string) *jobBatch {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.pending[key]
+	if !ok {
+		b = &jobBatch{key: key, done: make(chan struct{})}
+		t.pending[key] = b
+		time.AfterFunc(jobBatchWindow, func() { t.flush(b) })
+	}
+	b.uuids = append(b.uuids, jobUuid)
+	if len(b.uuids) >= maxJobBatch { // full, start a new batch for following jobs
+		delete(t.pending, key)
+		go t.flush(b)
+	}
+	return b
+}
+
+func (t *jobWatcher) flush(b *jobBatch) {
+	t.mu.Lock()
+	if b.flushed {
+		t.mu.Unlock()
+		return
+	}
+	b.flushed = true
+	if t.pending[b.key] == b {
+		delete(t.pending, b.key)
+	}
+	t.mu.Unlock()
+	defer close(b.done)
+
+	klog.V(5).Infof("polling %d jobs", len(b.uuids))
+	ctx, cancel := context.WithTimeout(context.Background(), jobBatchTimeout)
+	defer cancel()
+	resp, e := t.cli.JobResult(ctx, &compute.JobResultRequest{
+		Header:   &base.Header{RegionId: b.key.regionID, ZoneId: b.key.zoneID},
+		JobUuids: b.uuids,
+	})
+	if e != nil {
+		b.err = fmt.Errorf("job result error %w", e)
+		return
+	}
+	if resp.Error.Errno != 0 {
+		b.err = newAPIError("job result", resp.Error)
+		return
+	}
+
+	b.infos = make(map[string]*base.JobInfo, len(resp.Data))
+	for _, info := range resp.Data {
+		b.infos[info.JobUuid] = info
+	}
+}
@@ -0,0 +1,141 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"google.golang.org/grpc"
+)
+
+// fakeCommonClient answers JobResult with done jobs, after release is closed if set
+type fakeCommonClient struct {
+	compute.CommonClient
+	release chan struct{}
+
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (t *fakeCommonClient) JobResult(ctx context.Context, req *compute.JobResultRequest, opts ...grpc.CallOption) (*compute.JobResultResponse, error) {
+	t.mu.Lock()
+	t.batches = append(t.batches, req.JobUuids)
+	t.mu.Unlock()
+	if t.release != nil {
+		<-t.release
+	}
+
+	resp := &compute.JobResultResponse{Error: &base.Error{}}
+	for _, id := range req.JobUuids {
+		resp.Data = append(resp.Data, &base.JobInfo{JobUuid: id, ResourceUuid: "res-" + id, Done: true, Success: true})
+	}
+	return resp, nil
+}
+
+func (t *fakeCommonClient) batchSizes() []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var sizes []int
+	for _, b := range t.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+func TestWaitForJobsBatched(t *testing.T) {
+	fake := &fakeCommonClient{}
+	cli := &client{
+		poll: PollPolicy{InitialInterval: 10 * time.Millisecond}.withDefaults(),
+		jobs: newJobWatcher(fake),
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("job-%d", i)
+			info, e := cli.waitForJob(context.Background(), &base.JobInfo{JobUuid: id}, "gz", "gz02")
+			if e == nil && info.ResourceUuid != "res-"+id {
+				e = fmt.Errorf("job %s got result of %s", id, info.JobUuid)
+			}
+			errs[i] = e
+		}(i)
+	}
+	wg.Wait()
+
+	for _, e := range errs {
+		if e != nil {
+			t.Error(e)
+		}
+	}
+	if sizes := fake.batchSizes(); len(sizes) != 1 || sizes[0] != n {
+		t.Errorf("expect one JobResult call of %d jobs, got %v", n, sizes)
+	}
+}
+
+func TestJobBatchSplit(t *testing.T) {
+	fake := &fakeCommonClient{}
+	w := newJobWatcher(fake)
+
+	var wg sync.WaitGroup
+	for i := 0; i < maxJobBatch+1; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, e := w.result(context.Background(), "gz", "", fmt.Sprintf("job-%d", i)); e != nil {
+				t.Error(e)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	sizes := fake.batchSizes()
+	if len(sizes) != 2 || sizes[0]+sizes[1] != maxJobBatch+1 || sizes[0] > maxJobBatch || sizes[1] > maxJobBatch {
+		t.Errorf("expect 2 batches of at most %d jobs, got %v", maxJobBatch, sizes)
+	}
+}
+
+func TestJobBatchWaiterCanceled(t *testing.T) {
+	fake := &fakeCommonClient{release: make(chan struct{})}
+	w := newJobWatcher(fake)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error, 1)
+	go func() {
+		_, e := w.result(ctx, "gz", "", "job-canceled")
+		canceled <- e
+	}()
+	done := make(chan error, 1)
+	go func() {
+		info, e := w.result(context.Background(), "gz", "", "job-waiting")
+		if e == nil && !info.Done {
+			e = errors.New("job not done")
+		}
+		done <- e
+	}()
+
+	// wait until the batch is querying
+	for len(fake.batchSizes()) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if e := <-canceled; !errors.Is(e, context.Canceled) {
+		t.Errorf("expect canceled, got %v", e)
+	}
+
+	close(fake.release)
+	if e := <-done; e != nil {
+		t.Error(e)
+	}
+	if sizes := fake.batchSizes(); len(sizes) != 1 || sizes[0] != 2 {
+		t.Errorf("expect one JobResult call of 2 jobs, got %v", sizes)
+	}
+}
@@ -0,0 +1,52 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+)
+
+// stopPaging could be returned by a page func to stop listing early without an error
+var stopPaging = errors.New("stop paging")
+
+// listPages calls page with increasing start until a page is shorter than limit
+func listPages(limit int32, page func(start, limit int32) (int, error)) error {
+	for start := int32(0); ; start += limit {
+		n, e := page(start, limit)
+		if e == stopPaging {
+			return nil
+		}
+		if e != nil {
+			return e
+		}
+		if n < int(limit) {
+			return nil
+		}
+	}
+}
+
+// listDc2 visits all dc2 matched by cond, until visit returns false
+func (t *client) listDc2(ctx context.Context, cond *compute.ListDc2Condition, visit func(*compute.Dc2Info) bool) error {
+	return listPages(maxDc2, func(start, limit int32) (int, error) {
+		resp, e := t.dc2.ListDc2(ctx, &compute.ListDc2Request{
+			Start:     start,
+			Limit:     limit,
+			Simplify:  true,
+			Condition: cond,
+		})
+		if e != nil {
+			return 0, fmt.Errorf("list dc2 error %w", e)
+		}
+		if resp.Error.Errno != 0 {
+			return 0, newAPIError("list dc2", resp.Error)
+		}
+		for _, d := range resp.Data {
+			if !visit(d) {
+				return 0, stopPaging
+			}
+		}
+		return len(resp.Data), nil
+	})
+}
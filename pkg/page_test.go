@@ -0,0 +1,121 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"google.golang.org/grpc"
+)
+
+func TestListPages(t *testing.T) {
+	errPage := errors.New("page error")
+	cases := []struct {
+		name   string
+		sizes  []int // items of each page
+		stopAt int   // page returning stopPaging, -1 for none
+		errAt  int   // page returning errPage, -1 for none
+		calls  int
+		err    error
+	}{
+		{"empty", []int{0}, -1, -1, 1, nil},
+		{"short page", []int{3}, -1, -1, 1, nil},
+		{"full then empty", []int{5, 0}, -1, -1, 2, nil},
+		{"full then short", []int{5, 5, 2}, -1, -1, 3, nil},
+		{"stop early", []int{5, 5, 5}, 1, -1, 2, nil},
+		{"error on later page", []int{5, 5, 5}, -1, 1, 2, errPage},
+	}
+	for _, c := range cases {
+		var starts []int32
+		e := listPages(5, func(start, limit int32) (int, error) {
+			i := len(starts)
+			starts = append(starts, start)
+			switch i {
+			case c.stopAt:
+				return 0, stopPaging
+			case c.errAt:
+				return 0, errPage
+			}
+			return c.sizes[i], nil
+		})
+		if e != c.err {
+			t.Errorf("%s: expect error %v, got %v", c.name, c.err, e)
+		}
+		if len(starts) != c.calls {
+			t.Errorf("%s: expect %d pages, got %d", c.name, c.calls, len(starts))
+		}
+		for i, s := range starts {
+			if s != int32(i*5) {
+				t.Errorf("%s: page %d starts at %d", c.name, i, s)
+			}
+		}
+	}
+}
+
+// fakeDc2Client pages ListDc2 over dc2s, ignoring conditions
+type fakeDc2Client struct {
+	compute.Dc2Client
+	dc2s  []*compute.Dc2Info
+	err   error
+	calls int
+}
+
+func (t *fakeDc2Client) ListDc2(ctx context.Context, req *compute.ListDc2Request, opts ...grpc.CallOption) (*compute.ListDc2Response, error) {
+	t.calls++
+	if t.err != nil {
+		return nil, t.err
+	}
+	resp := &compute.ListDc2Response{Error: &base.Error{}}
+	for i := req.Start; i < req.Start+req.Limit && int(i) < len(t.dc2s); i++ {
+		resp.Data = append(resp.Data, t.dc2s[i])
+	}
+	return resp, nil
+}
+
+func fakeDc2s(n int) []*compute.Dc2Info {
+	var dc2s []*compute.Dc2Info
+	for i := 0; i < n; i++ {
+		dc2s = append(dc2s, &compute.Dc2Info{
+			Dc2Uuid: fmt.Sprintf("uuid-%d", i),
+			Name:    fmt.Sprintf("node-%d", i),
+			Ip:      fmt.Sprintf("10.0.%d.%d", i/256, i%256),
+		})
+	}
+	return dc2s
+}
+
+func TestGetDc2UUIDsByNames(t *testing.T) {
+	fake := &fakeDc2Client{dc2s: fakeDc2s(maxDc2 + 1)}
+	cli := &client{dc2: fake}
+
+	uuids, missing, e := cli.getDc2UUIDsByNames(context.Background(), "", []string{"node-500", "node-0", "node-x"})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(uuids, []string{"uuid-500", "uuid-0"}) || !reflect.DeepEqual(missing, []string{"node-x"}) {
+		t.Errorf("got uuids %v, missing %v", uuids, missing)
+	}
+	if fake.calls != 2 {
+		t.Errorf("expect 2 pages listed, got %d", fake.calls)
+	}
+}
+
+func TestGetDc2UUIDsByNamesCached(t *testing.T) {
+	fake := &fakeDc2Client{dc2s: fakeDc2s(2)}
+	cli := &client{dc2: fake}
+	cli.cache = newDc2Cache(time.Minute, cli.listDc2)
+
+	for i := 0; i < 3; i++ {
+		if _, missing, e := cli.getDc2UUIDsByNames(context.Background(), "vpc-1", []string{"node-0", "node-x"}); e != nil || len(missing) != 1 {
+			t.Fatalf("got missing %v, error %v", missing, e)
+		}
+	}
+	if fake.calls != 1 {
+		t.Errorf("expect names never resolved not to refresh a fresh cache, got %d listings", fake.calls)
+	}
+}
@@ -0,0 +1,90 @@
+package pkg
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultPollInitialInterval = time.Second
+	defaultPollMaxInterval     = 10 * time.Second
+	defaultPollMultiplier      = 2
+	defaultPollJitter          = 0.2
+	defaultPollTimeout         = 10 * time.Minute
+)
+
+// PollPolicy controls how often an async job is polled until it is done, zero values are replaced by defaults
+type PollPolicy struct {
+	// InitialInterval is the delay before the first poll
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between two polls
+	MaxInterval time.Duration
+	// Multiplier grows the delay after every poll
+	Multiplier float64
+	// Jitter randomizes every delay by up to this fraction, eg. 0.2 for ±20%
+	Jitter float64
+	// Timeout limits the overall time waiting for a single job, negative for no limit
+	Timeout time.Duration
+}
+
+func (t PollPolicy) withDefaults() PollPolicy {
+	if t.InitialInterval <= 0 {
+		t.InitialInterval = defaultPollInitialInterval
+	}
+	if t.MaxInterval <= 0 {
+		t.MaxInterval = defaultPollMaxInterval
+	}
+	if t.MaxInterval < t.InitialInterval {
+		t.MaxInterval = t.InitialInterval
+	}
+	if t.Multiplier < 1 {
+		t.Multiplier = defaultPollMultiplier
+	}
+	if t.Jitter <= 0 || t.Jitter >= 1 {
+		t.Jitter = defaultPollJitter
+	}
+	if t.Timeout == 0 {
+		t.Timeout = defaultPollTimeout
+	}
+	return t
+}
+
+// withTimeout bounds ctx by the policy timeout
+func (t PollPolicy) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t.Timeout < 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, t.Timeout)
+}
+
+func (t PollPolicy) backoff() *backoff {
+	return &backoff{policy: t, next: t.InitialInterval}
+}
+
+// backoff yields exponentially growing delays with jitter
+type backoff struct {
+	policy PollPolicy
+	next   time.Duration
+}
+
+func (t *backoff) delay() time.Duration {
+	d := t.next
+	t.next = time.Duration(float64(t.next) * t.policy.Multiplier)
+	if t.next > t.policy.MaxInterval {
+		t.next = t.policy.MaxInterval
+	}
+	return time.Duration(float64(d) * (1 + t.policy.Jitter*(2*rand.Float64()-1)))
+}
+
+// wait sleeps for the next delay, or returns early with the error of ctx
+func (t *backoff) wait(ctx context.Context) error {
+	timer := time.NewTimer(t.delay())
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+package pkg
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+)
+
+// RateLimit is a token bucket, zero QPS means unlimited
+type RateLimit struct {
+	// QPS is the refill rate of tokens per second
+	QPS float64
+	// Burst is the bucket size, at least 1
+	Burst int
+}
+
+func (t RateLimit) limiter() *rate.Limiter {
+	if t.QPS <= 0 {
+		return nil
+	}
+	burst := t.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(t.QPS), burst)
+}
+
+// rateLimiter throttles requests by a global bucket shared by all methods, and by buckets of single methods
+type rateLimiter struct {
+	global  *rate.Limiter
+	methods map[string]*rate.Limiter
+}
+
+func newRateLimiter(global RateLimit, methods map[string]RateLimit) *rateLimiter {
+	t := &rateLimiter{
+		global:  global.limiter(),
+		methods: make(map[string]*rate.Limiter, len(methods)),
+	}
+	for m, l := range methods {
+		if lim := l.limiter(); lim != nil {
+			t.methods[m] = lim
+		}
+	}
+	return t
+}
+
+func (t *rateLimiter) intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if lim, ok := t.methods[methodName(method)]; ok {
+		if e := lim.Wait(ctx); e != nil {
+			return e
+		}
+	}
+	if t.global != nil {
+		if e := t.global.Wait(ctx); e != nil {
+			return e
+		}
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
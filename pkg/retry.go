@@ -0,0 +1,146 @@
+package pkg
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog"
+)
+
+const (
+	defaultRetryMaxAttempts     = 3
+	defaultRetryInitialInterval = 500 * time.Millisecond
+	defaultRetryMaxInterval     = 5 * time.Second
+)
+
+var (
+	// methods only reading resources, which are always safe to retry.
+	// Mutations are not retried by default even if they converge to the same state, since a replay of one that
+	// reached the server reports an api error, eg. "找不到指定EBS" for deleting an ebs already deleted
+	readMethodPrefixes = []string{"Get", "List", "Check", "JobResult"}
+
+	// api errors caused by throttling
+	rateLimitPhrases = []string{"频繁", "限流"}
+)
+
+// RetryPolicy controls retrying of transient errors, zero values are replaced by defaults
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt, 1 disables retrying
+	MaxAttempts int
+	// InitialInterval and MaxInterval bounds the exponential backoff between attempts
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	// Errnos are api errnos worth retrying, in addition to rate limited errors
+	Errnos []int32
+	// Methods are grpc method names safe to retry in addition to reads, eg. "DetachEbs" if the caller tolerates
+	// an error of replaying one already done
+	Methods []string
+}
+
+func (t RetryPolicy) withDefaults() RetryPolicy {
+	if t.MaxAttempts <= 0 {
+		t.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if t.InitialInterval <= 0 {
+		t.InitialInterval = defaultRetryInitialInterval
+	}
+	if t.MaxInterval <= 0 {
+		t.MaxInterval = defaultRetryMaxInterval
+	}
+	return t
+}
+
+// RetryCounter counts retries of all requests issued with a context from WithRetryCounter
+type RetryCounter struct {
+	retries int64
+}
+
+// Retries returns the number of retries so far
+func (t *RetryCounter) Retries() int64 {
+	return atomic.LoadInt64(&t.retries)
+}
+
+type retryCounterKey struct{}
+
+// WithRetryCounter returns a context counting retries into c
+func WithRetryCounter(ctx context.Context, c *RetryCounter) context.Context {
+	return context.WithValue(ctx, retryCounterKey{}, c)
+}
+
+type retrier struct {
+	policy RetryPolicy
+}
+
+func (t *retrier) intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if t.policy.MaxAttempts <= 1 || !t.retryableMethod(method) {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	b := PollPolicy{InitialInterval: t.policy.InitialInterval, MaxInterval: t.policy.MaxInterval}.withDefaults().backoff()
+	for attempt := 1; ; attempt++ {
+		e := invoker(ctx, method, req, reply, cc, opts...)
+		if attempt >= t.policy.MaxAttempts || !t.retryable(e, reply) {
+			return e
+		}
+
+		klog.V(4).Infof("retrying %s after attempt %d, error %v", method, attempt, e)
+		if b.wait(ctx) != nil { // give up with the last error
+			return e
+		}
+		if c, ok := ctx.Value(retryCounterKey{}).(*RetryCounter); ok {
+			atomic.AddInt64(&c.retries, 1)
+		}
+	}
+}
+
+func (t *retrier) retryableMethod(method string) bool {
+	name := methodName(method)
+	for _, m := range t.policy.Methods {
+		if m == name {
+			return true
+		}
+	}
+	for _, p := range readMethodPrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *retrier) retryable(e error, reply interface{}) bool {
+	if e != nil {
+		switch status.Code(e) {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+			return true
+		}
+		return false
+	}
+
+	r, ok := reply.(interface{ GetError() *base.Error })
+	if !ok || r.GetError().GetErrno() == 0 {
+		return false
+	}
+	for _, n := range t.policy.Errnos {
+		if r.GetError().GetErrno() == n {
+			return true
+		}
+	}
+	for _, p := range rateLimitPhrases {
+		if strings.Contains(r.GetError().GetErrmsg(), p) {
+			return true
+		}
+	}
+	return false
+}
+
+// methodName trims the service of a full grpc method, eg. "/didi.cloud.compute.v1.Ebs/GetEbsByUuid" to "GetEbsByUuid"
+func methodName(method string) string {
+	return method[strings.LastIndex(method, "/")+1:]
+}
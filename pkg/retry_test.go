@@ -0,0 +1,106 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryableMethod(t *testing.T) {
+	r := &retrier{policy: RetryPolicy{Methods: []string{"ChangeEbsSize"}}.withDefaults()}
+	cases := map[string]bool{
+		"/didi.cloud.compute.v1.Ebs/GetEbsByUuid":     true,
+		"/didi.cloud.compute.v1.Ebs/ListEbs":          true,
+		"/didi.cloud.compute.v1.Common/JobResult":     true,
+		"/didi.cloud.compute.v1.Vpc/CheckVpcName":     true,
+		"/didi.cloud.compute.v1.Ebs/ChangeEbsSize":    true,
+		"/didi.cloud.compute.v1.Ebs/CreateEbs":        false,
+		"/didi.cloud.compute.v1.Ebs/AttachEbs":        false,
+		"/didi.cloud.compute.v1.Ebs/DetachEbs":        false,
+		"/didi.cloud.compute.v1.Ebs/DeleteEbs":        false,
+		"/didi.cloud.compute.v1.Dc2/StopDc2":          false,
+		"/didi.cloud.compute.v1.Ebs/ChangeEbsSizeXXX": false,
+	}
+	for method, expect := range cases {
+		if got := r.retryableMethod(method); got != expect {
+			t.Errorf("retryableMethod(%s) = %v, expect %v", method, got, expect)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	r := &retrier{policy: RetryPolicy{Errnos: []int32{500}}.withDefaults()}
+	reply := func(errno int32, msg string) interface{} {
+		return &compute.GetEbsByUuidResponse{Error: &base.Error{Errno: errno, Errmsg: msg}}
+	}
+	cases := []struct {
+		name   string
+		err    error
+		reply  interface{}
+		expect bool
+	}{
+		{"unavailable", status.Error(codes.Unavailable, "conn reset"), nil, true},
+		{"deadline", status.Error(codes.DeadlineExceeded, "timeout"), nil, true},
+		{"exhausted", status.Error(codes.ResourceExhausted, "too many"), nil, true},
+		{"unauthenticated", status.Error(codes.Unauthenticated, "bad token"), nil, false},
+		{"invalid", status.Error(codes.InvalidArgument, "bad request"), nil, false},
+		{"not grpc", errors.New("other"), nil, false},
+		{"ok", nil, reply(0, ""), false},
+		{"configured errno", nil, reply(500, "内部错误"), true},
+		{"other errno", nil, reply(41070, "查询SLB信息失败"), false},
+		{"too frequent", nil, reply(1, "请求过于频繁"), true},
+		{"throttled", nil, reply(1, "触发限流"), true},
+		{"no error field", nil, struct{}{}, false},
+	}
+	for _, c := range cases {
+		if got := r.retryable(c.err, c.reply); got != c.expect {
+			t.Errorf("%s: retryable = %v, expect %v", c.name, got, c.expect)
+		}
+	}
+}
+
+func TestRetryInterceptor(t *testing.T) {
+	r := &retrier{policy: RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond}.withDefaults()}
+
+	var calls int
+	flaky := func(failures int) grpc.UnaryInvoker {
+		calls = 0
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			calls++
+			if calls <= failures {
+				return status.Error(codes.Unavailable, "conn reset")
+			}
+			return nil
+		}
+	}
+
+	var c RetryCounter
+	ctx := WithRetryCounter(context.Background(), &c)
+	if e := r.intercept(ctx, "/didi.cloud.compute.v1.Ebs/GetEbsByUuid", nil, nil, nil, flaky(2)); e != nil {
+		t.Errorf("expect success after retries, got %v", e)
+	}
+	if calls != 3 || c.Retries() != 2 {
+		t.Errorf("expect 3 calls and 2 retries, got %d calls and %d retries", calls, c.Retries())
+	}
+
+	if e := r.intercept(ctx, "/didi.cloud.compute.v1.Ebs/GetEbsByUuid", nil, nil, nil, flaky(5)); status.Code(e) != codes.Unavailable {
+		t.Errorf("expect the last error, got %v", e)
+	}
+	if calls != 3 || c.Retries() != 4 {
+		t.Errorf("expect 3 calls and 4 retries in total, got %d calls and %d retries", calls, c.Retries())
+	}
+
+	if e := r.intercept(ctx, "/didi.cloud.compute.v1.Ebs/DetachEbs", nil, nil, nil, flaky(1)); status.Code(e) != codes.Unavailable {
+		t.Errorf("expect mutations not retried, got %v", e)
+	}
+	if calls != 1 || c.Retries() != 4 {
+		t.Errorf("expect 1 call and no more retries, got %d calls and %d retries", calls, c.Retries())
+	}
+}
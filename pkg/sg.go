@@ -0,0 +1,356 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"k8s.io/klog"
+)
+
+const (
+	maxSg     = 100
+	maxSgRule = 100
+
+	SgRuleIngress = "Ingress"
+	SgRuleEgress  = "Egress"
+)
+
+type SgClient interface {
+	Create(ctx context.Context, regionID, vpcUUID, name string, rules []SgRule) (string, error)
+	List(ctx context.Context, regionID string, filter *SgFilter) ([]*compute.SgInfo, error)
+	Delete(ctx context.Context, sgUUID string) error
+	Bind(ctx context.Context, sgUUID string, dc2UUIDs []string) error
+	Unbind(ctx context.Context, sgUUID string, dc2UUIDs []string) error
+	SyncRules(ctx context.Context, sgUUID string, rules []SgRule) error
+}
+
+// SgRule allows traffic of the protocol and port range from (ingress) or to (egress) the cidr
+type SgRule struct {
+	Type        string // SgRuleIngress or SgRuleEgress
+	Protocol    string // eg. "TCP", "UDP", "ICMP"
+	StartPort   int64
+	EndPort     int64
+	AllowedCidr string
+}
+
+func (t SgRule) key() string {
+	return strings.ToLower(fmt.Sprintf("%s/%s/%d-%d/%s", t.Type, t.Protocol, t.StartPort, t.EndPort, t.AllowedCidr))
+}
+
+// SgFilter selects security groups to list, empty fields match all
+type SgFilter struct {
+	Uuids   []string
+	VpcUuid string
+	Dc2Uuid string
+}
+
+type sgClient struct {
+	cli compute.SgClient
+	helper
+}
+
+var _ SgClient = (*sgClient)(nil)
+
+func (t *sgClient) Create(ctx context.Context, regionID, vpcUUID, name string, rules []SgRule) (string, error) {
+	klog.V(4).Infof("creating security group %s in vpc %s", name, vpcUUID)
+	req := &compute.CreateSgRequest{
+		Header:  &base.Header{RegionId: regionID},
+		Name:    name,
+		VpcUuid: vpcUUID,
+		SgRule:  sgRuleInputs(rules),
+	}
+	resp, e := t.cli.CreateSg(ctx, req)
+	if e != nil {
+		return "", fmt.Errorf("create security group error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return "", newAPIError("create security group", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], regionID, "")
+	if e != nil {
+		return "", e
+	}
+	if !job.Success { // uuid is returned if created anyway, so that it could be cleaned up
+		return job.ResourceUuid, newJobError("create security group", job)
+	}
+	return job.ResourceUuid, nil
+}
+
+func (t *sgClient) List(ctx context.Context, regionID string, filter *SgFilter) ([]*compute.SgInfo, error) {
+	klog.V(4).Infof("listing security groups in region %s", regionID)
+	var cond *compute.ListSgCondition
+	if filter != nil {
+		cond = &compute.ListSgCondition{
+			SgUuids: filter.Uuids,
+			VpcUuid: filter.VpcUuid,
+			Dc2Uuid: filter.Dc2Uuid,
+		}
+	}
+
+	var sgs []*compute.SgInfo
+	e := listPages(maxSg, func(start, limit int32) (int, error) {
+		resp, e := t.cli.ListSg(ctx, &compute.ListSgRequest{
+			Header:    &base.Header{RegionId: regionID},
+			Start:     start,
+			Limit:     limit,
+			Condition: cond,
+		})
+		if e != nil {
+			return 0, fmt.Errorf("list security group error %w", e)
+		}
+		if resp.Error.Errno != 0 {
+			return 0, newAPIError("list security group", resp.Error)
+		}
+		sgs = append(sgs, resp.Data...)
+		return len(resp.Data), nil
+	})
+	if e != nil {
+		return nil, e
+	}
+	return sgs, nil
+}
+
+func (t *sgClient) Delete(ctx context.Context, sgUUID string) error {
+	klog.V(4).Infof("deleting security group %s", sgUUID)
+	req := &compute.DeleteSgRequest{
+		Sg: []*compute.DeleteSgRequest_Input{{SgUuid: sgUUID}},
+	}
+	resp, e := t.cli.DeleteSg(ctx, req)
+	if e != nil {
+		return fmt.Errorf("delete security group error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("delete security group", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success { // not found etc.
+		return newJobError("delete security group", job)
+	}
+	return nil
+}
+
+func (t *sgClient) Bind(ctx context.Context, sgUUID string, dc2UUIDs []string) error {
+	if len(dc2UUIDs) == 0 {
+		return nil
+	}
+
+	klog.V(4).Infof("binding dc2 %v to security group %s", dc2UUIDs, sgUUID)
+	req := &compute.AttachDc2ToSgRequest{
+		Sg: []*compute.AttachDc2ToSgRequest_Sg{{SgUuid: sgUUID}},
+	}
+	for _, d := range dc2UUIDs {
+		req.Dc2 = append(req.Dc2, &compute.AttachDc2ToSgRequest_Dc2{Dc2Uuid: d})
+	}
+	resp, e := t.cli.AttachDc2ToSg(ctx, req)
+	if e != nil {
+		return fmt.Errorf("bind security group error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("bind security group", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success {
+		return newJobError("bind security group", job)
+	}
+	return nil
+}
+
+func (t *sgClient) Unbind(ctx context.Context, sgUUID string, dc2UUIDs []string) error {
+	if len(dc2UUIDs) == 0 {
+		return nil
+	}
+
+	klog.V(4).Infof("unbinding dc2 %v from security group %s", dc2UUIDs, sgUUID)
+	req := &compute.DetachDc2FromSgRequest{
+		Sg: []*compute.DetachDc2FromSgRequest_Sg{{SgUuid: sgUUID}},
+	}
+	for _, d := range dc2UUIDs {
+		req.Dc2 = append(req.Dc2, &compute.DetachDc2FromSgRequest_Dc2{Dc2Uuid: d})
+	}
+	resp, e := t.cli.DetachDc2FromSg(ctx, req)
+	if e != nil {
+		return fmt.Errorf("unbind security group error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("unbind security group", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success {
+		return newJobError("unbind security group", job)
+	}
+	return nil
+}
+
+// SyncRules makes rules of the security group exactly the desired ones, default rules of the group are kept
+func (t *sgClient) SyncRules(ctx context.Context, sgUUID string, rules []SgRule) error {
+	if sgUUID == "" { // if uuid is empty, rules of all security groups will be listed which is not expected
+		return errors.New("empty security group uuid")
+	}
+
+	klog.V(4).Infof("syncing rules of security group %s", sgUUID)
+	current, e := t.listRules(ctx, sgUUID)
+	if e != nil {
+		return e
+	}
+
+	existRules := make(map[string][]*compute.SgRuleInfo, len(current)) // duplicates of a key are all tracked
+	for _, r := range current {
+		if r.IsDefault {
+			continue
+		}
+		k := SgRule{
+			Type:        r.Type,
+			Protocol:    r.Protocol,
+			StartPort:   r.StartPort,
+			EndPort:     r.EndPort,
+			AllowedCidr: r.AllowedCidr,
+		}.key()
+		existRules[k] = append(existRules[k], r)
+	}
+
+	var createRules []SgRule
+	var deleteRules []string                 // rule uuid
+	for _, r := range uniqueSgRules(rules) { // empty rules will remove all existing rules
+		k := r.key()
+		if exist := existRules[k]; len(exist) > 0 {
+			existRules[k] = exist[1:] // keep one, delete other duplicates
+		} else {
+			createRules = append(createRules, r)
+		}
+	}
+	// delete left
+	for _, rs := range existRules {
+		for _, r := range rs {
+			deleteRules = append(deleteRules, r.SgRuleUuid)
+		}
+	}
+
+	// create before delete, so traffic allowed by both is never interrupted
+	if e := t.createRules(ctx, sgUUID, createRules); e != nil {
+		return e
+	}
+	if e := t.deleteRules(ctx, deleteRules); e != nil {
+		return e
+	}
+	return nil
+}
+
+func (t *sgClient) listRules(ctx context.Context, sgUUID string) ([]*compute.SgRuleInfo, error) {
+	var rules []*compute.SgRuleInfo
+	e := listPages(maxSgRule, func(start, limit int32) (int, error) {
+		resp, e := t.cli.ListSgRule(ctx, &compute.ListSgRuleRequest{
+			Start:     start,
+			Limit:     limit,
+			Condition: &compute.ListSgRuleCondition{SgUuid: sgUUID},
+		})
+		if e != nil {
+			return 0, fmt.Errorf("list rules of security group error %w", e)
+		}
+		if resp.Error.Errno != 0 {
+			return 0, newAPIError("list rules of security group", resp.Error)
+		}
+		rules = append(rules, resp.Data...)
+		return len(resp.Data), nil
+	})
+	return rules, e
+}
+
+func (t *sgClient) createRules(ctx context.Context, sgUUID string, rules []SgRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	klog.V(4).Infof("creating rules of security group %s", sgUUID)
+	req := &compute.CreateSgRuleRequest{
+		SgUuid: sgUUID,
+		SgRule: sgRuleInputs(rules),
+	}
+	resp, e := t.cli.CreateSgRule(ctx, req)
+	if e != nil {
+		return fmt.Errorf("create rules of security group error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("create rules of security group", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success {
+		return newJobError("create rules of security group", job)
+	}
+	return nil
+}
+
+func (t *sgClient) deleteRules(ctx context.Context, ruleUuids []string) error {
+	if len(ruleUuids) == 0 {
+		return nil
+	}
+
+	klog.V(4).Infof("deleting rules of security group")
+	req := &compute.DeleteSgRuleRequest{}
+	for _, r := range ruleUuids {
+		req.SgRule = append(req.SgRule, &compute.DeleteSgRuleRequest_Input{SgRuleUuid: r})
+	}
+	resp, e := t.cli.DeleteSgRule(ctx, req)
+	if e != nil {
+		return fmt.Errorf("delete rules of security group error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("delete rules of security group", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success {
+		return newJobError("delete rules of security group", job)
+	}
+	return nil
+}
+
+// uniqueSgRules drops rules with the same key as a former one
+func uniqueSgRules(rules []SgRule) []SgRule {
+	seen := make(map[string]bool, len(rules))
+	var unique []SgRule
+	for _, r := range rules {
+		if k := r.key(); !seen[k] {
+			seen[k] = true
+			unique = append(unique, r)
+		}
+	}
+	return unique
+}
+
+func sgRuleInputs(rules []SgRule) []*compute.CreateSgRuleInput {
+	var inputs []*compute.CreateSgRuleInput
+	for _, r := range rules {
+		inputs = append(inputs, &compute.CreateSgRuleInput{
+			Type:        r.Type,
+			Protocol:    r.Protocol,
+			StartPort:   r.StartPort,
+			EndPort:     r.EndPort,
+			AllowedCidr: r.AllowedCidr,
+		})
+	}
+	return inputs
+}
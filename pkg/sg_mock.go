@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"github.com/pborman/uuid"
+)
+
+type sgInfo struct {
+	sg    *compute.SgInfo
+	rules []SgRule
+	dc2   map[string]bool
+}
+
+type mockSgClient struct {
+	sg     map[string]*sgInfo
+	client *mockClient
+}
+
+var _ SgClient = (*mockSgClient)(nil)
+
+func (t *mockSgClient) Create(ctx context.Context, regionID, vpcUUID, name string, rules []SgRule) (string, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return "", e
+	}
+	id := uuid.NewUUID().String()
+	t.sg[id] = &sgInfo{
+		sg:    &compute.SgInfo{SgUuid: id, Name: name, Vpc: &compute.VpcInfo{VpcUuid: vpcUUID}},
+		rules: rules,
+		dc2:   make(map[string]bool),
+	}
+	return id, nil
+}
+
+func (t *mockSgClient) List(ctx context.Context, regionID string, filter *SgFilter) ([]*compute.SgInfo, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	var sgs []*compute.SgInfo
+	for _, s := range t.sg {
+		if filter != nil && (filter.VpcUuid != "" && s.sg.Vpc.VpcUuid != filter.VpcUuid || filter.Dc2Uuid != "" && !s.dc2[filter.Dc2Uuid]) {
+			continue
+		}
+		s.sg.Dc2Cnt = int64(len(s.dc2))
+		s.sg.SgRuleCnt = int64(len(s.rules))
+		sgs = append(sgs, s.sg)
+	}
+	return sgs, nil
+}
+
+func (t *mockSgClient) Delete(ctx context.Context, sgUUID string) error {
+	if _, e := t.get(sgUUID); e != nil {
+		return e
+	}
+	delete(t.sg, sgUUID)
+	return nil
+}
+
+func (t *mockSgClient) Bind(ctx context.Context, sgUUID string, dc2UUIDs []string) error {
+	s, e := t.get(sgUUID)
+	if e != nil {
+		return e
+	}
+	for _, d := range dc2UUIDs {
+		s.dc2[d] = true
+	}
+	return nil
+}
+
+func (t *mockSgClient) Unbind(ctx context.Context, sgUUID string, dc2UUIDs []string) error {
+	s, e := t.get(sgUUID)
+	if e != nil {
+		return e
+	}
+	for _, d := range dc2UUIDs {
+		delete(s.dc2, d)
+	}
+	return nil
+}
+
+func (t *mockSgClient) SyncRules(ctx context.Context, sgUUID string, rules []SgRule) error {
+	s, e := t.get(sgUUID)
+	if e != nil {
+		return e
+	}
+	s.rules = uniqueSgRules(rules)
+	return nil
+}
+
+func (t *mockSgClient) get(sgUUID string) (*sgInfo, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	s, ok := t.sg[sgUUID]
+	if !ok {
+		return nil, fmt.Errorf("security group %s %w", sgUUID, NotFound)
+	}
+	return s, nil
+}
@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"google.golang.org/grpc"
+)
+
+// fakeSgClient lists the rules, and records created and deleted ones with jobs done at once
+type fakeSgClient struct {
+	compute.SgClient
+	rules   []*compute.SgRuleInfo
+	created []string // rule keys
+	deleted []string // rule uuids
+}
+
+func (t *fakeSgClient) ListSgRule(ctx context.Context, req *compute.ListSgRuleRequest, opts ...grpc.CallOption) (*compute.ListSgRuleResponse, error) {
+	resp := &compute.ListSgRuleResponse{Error: &base.Error{}}
+	if req.Start == 0 {
+		resp.Data = t.rules
+	}
+	return resp, nil
+}
+
+func (t *fakeSgClient) CreateSgRule(ctx context.Context, req *compute.CreateSgRuleRequest, opts ...grpc.CallOption) (*compute.CreateSgRuleResponse, error) {
+	for _, r := range req.SgRule {
+		t.created = append(t.created, SgRule{Type: r.Type, Protocol: r.Protocol, StartPort: r.StartPort, EndPort: r.EndPort, AllowedCidr: r.AllowedCidr}.key())
+	}
+	return &compute.CreateSgRuleResponse{Error: &base.Error{}, Data: []*base.JobInfo{{Done: true, Success: true}}}, nil
+}
+
+func (t *fakeSgClient) DeleteSgRule(ctx context.Context, req *compute.DeleteSgRuleRequest, opts ...grpc.CallOption) (*compute.DeleteSgRuleResponse, error) {
+	for _, r := range req.SgRule {
+		t.deleted = append(t.deleted, r.SgRuleUuid)
+	}
+	return &compute.DeleteSgRuleResponse{Error: &base.Error{}, Data: []*base.JobInfo{{Done: true, Success: true}}}, nil
+}
+
+func TestSyncRulesDuplicates(t *testing.T) {
+	ssh := SgRule{Type: SgRuleIngress, Protocol: "TCP", StartPort: 22, EndPort: 22, AllowedCidr: "0.0.0.0/0"}
+	http := SgRule{Type: SgRuleIngress, Protocol: "TCP", StartPort: 80, EndPort: 80, AllowedCidr: "0.0.0.0/0"}
+	dns := SgRule{Type: SgRuleEgress, Protocol: "UDP", StartPort: 53, EndPort: 53, AllowedCidr: "0.0.0.0/0"}
+	info := func(uuid string, r SgRule, isDefault bool) *compute.SgRuleInfo {
+		return &compute.SgRuleInfo{SgRuleUuid: uuid, Type: r.Type, Protocol: r.Protocol, StartPort: r.StartPort, EndPort: r.EndPort, AllowedCidr: r.AllowedCidr, IsDefault: isDefault}
+	}
+
+	fake := &fakeSgClient{rules: []*compute.SgRuleInfo{
+		info("ssh-1", ssh, false),
+		info("ssh-2", ssh, false), // duplicate on the server
+		info("dns-default", dns, true),
+		info("dns-1", dns, false),
+	}}
+	sg := &sgClient{cli: fake, helper: &client{}}
+
+	if e := sg.SyncRules(context.Background(), "sg-1", []SgRule{ssh, http, http, ssh}); e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(fake.created, []string{http.key()}) {
+		t.Errorf("expect http created once, got %v", fake.created)
+	}
+	sort.Strings(fake.deleted)
+	if !reflect.DeepEqual(fake.deleted, []string{"dns-1", "ssh-2"}) {
+		t.Errorf("expect dns and the duplicate ssh deleted, got %v", fake.deleted)
+	}
+}
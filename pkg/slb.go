@@ -12,6 +12,7 @@ import (
 
 const (
 	maxSlbListeners  = 100
+	maxSlbMembers    = 500
 	defaultAlgorithm = "wrr"
 )
 
@@ -30,6 +31,15 @@ type SlbClient interface {
 	Delete(ctx context.Context, uuid string) error
 	SyncListeners(ctx context.Context, uuid string, listeners []*Listener, dc2Names []string) error
 	SyncListenerMembers(ctx context.Context, uuid string, dc2Names []string) error
+	WaitFor(ctx context.Context, uuid string, cond SlbPredicate) (*compute.SlbInfo, error)
+}
+
+// SlbPredicate tells whether a slb reaches the state waited for, an error stops waiting at a terminal state
+type SlbPredicate func(*compute.SlbInfo) (bool, error)
+
+// SlbExternalIPAssigned holds when the slb gets its external ip
+func SlbExternalIPAssigned(info *compute.SlbInfo) (bool, error) {
+	return info.GetBeip().GetIp() != "", nil
 }
 
 type slbClient struct {
@@ -59,7 +69,7 @@ func (t *slbClient) Create(ctx context.Context, regionID, zoneID, name string, b
 		return "", fmt.Errorf("create slb error %w", e)
 	}
 	if resp.Error.Errno != 0 {
-		return "", fmt.Errorf("create slb error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return "", newAPIError("create slb", resp.Error)
 	}
 
 	job, e := t.waitForJob(ctx, resp.Data[0], regionID, zoneID)
@@ -70,27 +80,57 @@ func (t *slbClient) Create(ctx context.Context, regionID, zoneID, name string, b
 		if job.ResourceUuid != "" { // not success, but still got uuid that already created
 			return job.ResourceUuid, nil
 		}
-		return "", fmt.Errorf("failed to create slb: %s", job.Result)
+		return "", newJobError("create slb", job)
 	}
 	return job.ResourceUuid, nil
 }
 
 func (t *slbClient) GetExternalIP(ctx context.Context, uuid string) (string, error) {
 	klog.V(4).Infof("getting external ip of slb uuid %s", uuid)
+	info, e := t.get(ctx, uuid)
+	if e != nil {
+		return "", e
+	}
+	return info.GetBeip().GetIp(), nil
+}
+
+func (t *slbClient) get(ctx context.Context, uuid string) (*compute.SlbInfo, error) {
 	req := &compute.GetSLBByUuidRequest{
 		SlbUuid: uuid,
 	}
 	resp, e := t.cli.GetSLBByUuid(ctx, req)
 	if e != nil {
-		return "", fmt.Errorf("get slb error %w", e)
+		return nil, fmt.Errorf("get slb error %w", e)
 	}
 	if resp.Error.Errno != 0 {
-		if resp.Error.Errno == slbNotFoundCode {
-			return "", fmt.Errorf("get slb error: %w", NotFound)
-		}
-		return "", fmt.Errorf("get slb error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return nil, newAPIError("get slb", resp.Error)
 	}
-	return resp.Data[0].Beip.Ip, nil
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("slb %s %w", uuid, NotFound)
+	}
+	return resp.Data[0], nil
+}
+
+// WaitFor polls the slb until cond holds, returns the last got slb.
+// It stops with an error if ctx ends, the slb is not found, cond returns an error, or the last job of the slb failed.
+// Like waiting for a job, it also gives up once the JobPoll.Timeout of the config passes
+func (t *slbClient) WaitFor(ctx context.Context, uuid string, cond SlbPredicate) (*compute.SlbInfo, error) {
+	klog.V(4).Infof("waiting for slb %s", uuid)
+	var info *compute.SlbInfo
+	e := t.pollUntil(ctx, "slb "+uuid, func(ctx context.Context) (bool, error) {
+		var e error
+		if info, e = t.get(ctx, uuid); e != nil {
+			return false, e
+		}
+		if ok, e := cond(info); ok || e != nil {
+			return ok, e
+		}
+		if job := info.GetJob(); job.GetDone() && !job.GetSuccess() {
+			return false, newJobError("wait for slb "+uuid, job)
+		}
+		return false, nil
+	})
+	return info, e
 }
 
 func (t *slbClient) Delete(ctx context.Context, uuid string) error {
@@ -103,7 +143,7 @@ func (t *slbClient) Delete(ctx context.Context, uuid string) error {
 		return fmt.Errorf("delete slb error %w", e)
 	}
 	if resp.Error.Errno != 0 {
-		return fmt.Errorf("delete slb error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return newAPIError("delete slb", resp.Error)
 	}
 
 	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
@@ -111,10 +151,7 @@ func (t *slbClient) Delete(ctx context.Context, uuid string) error {
 		return e
 	}
 	if !job.Success { // not found etc.
-		if job.Result == slbNotFoundMsg {
-			return fmt.Errorf("failed to delete slb: %w", NotFound)
-		}
-		return fmt.Errorf("failed to delete slb: %s", job.Result)
+		return newJobError("delete slb", job)
 	}
 	return nil
 }
@@ -133,21 +170,13 @@ func (t *slbClient) SyncListeners(ctx context.Context, uuid string, listeners []
 	}
 
 	klog.V(4).Infof("syncing listeners of slb %s", uuid)
-	req := &compute.ListSLBListenerRequest{
-		Start:     0,
-		Limit:     maxSlbListeners,
-		Condition: &compute.ListSLBListenerRequest_Condition{SlbUuid: uuid},
-	}
-	resp, e := t.cli.ListSLBListener(ctx, req)
+	current, e := t.listListeners(ctx, uuid)
 	if e != nil {
-		return fmt.Errorf("list listeners of slb error %w", e)
-	}
-	if resp.Error.Errno != 0 {
-		return fmt.Errorf("list listeners of slb error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return e
 	}
 
-	existLis := make(map[string]*compute.ListSLBListenerResponse_Data, len(resp.Data))
-	for _, l := range resp.Data {
+	existLis := make(map[string]*compute.ListSLBListenerResponse_Data, len(current))
+	for _, l := range current {
 		existLis[l.Name] = l
 	}
 
@@ -186,8 +215,11 @@ func (t *slbClient) SyncListeners(ctx context.Context, uuid string, listeners []
 		return e
 	}
 
+	var missing []string
 	if len(createLis) > 0 {
-		dc2Uuids, e := t.getDc2UUIDsByNames(ctx, t.vpcUuid, dc2Names)
+		var dc2Uuids []string
+		var e error
+		dc2Uuids, missing, e = t.getDc2UUIDsByNames(ctx, t.vpcUuid, dc2Names)
 		if e != nil {
 			return e
 		}
@@ -201,7 +233,15 @@ func (t *slbClient) SyncListeners(ctx context.Context, uuid string, listeners []
 		return e
 	}
 
-	return nil
+	return t.missingDc2(missing)
+}
+
+// missingDc2 returns a Dc2NotFoundError of names not resolved, once others are synced
+func (t *slbClient) missingDc2(missing []string) error {
+	if len(missing) == 0 {
+		return nil
+	}
+	return &Dc2NotFoundError{VpcUuid: t.vpcUuid, Names: missing}
 }
 
 func (t *slbClient) createListeners(ctx context.Context, uuid string, listeners []*Listener, dc2Members []string) error {
@@ -239,7 +279,7 @@ func (t *slbClient) createListeners(ctx context.Context, uuid string, listeners
 		return fmt.Errorf("create listeners of slb error %w", e)
 	}
 	if resp.Error.Errno != 0 {
-		return fmt.Errorf("create listeners of slb error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return newAPIError("create listeners of slb", resp.Error)
 	}
 
 	job, e := t.waitForJob(ctx, resp.Data, "", "")
@@ -247,7 +287,7 @@ func (t *slbClient) createListeners(ctx context.Context, uuid string, listeners
 		return e
 	}
 	if !job.Success {
-		return fmt.Errorf("failed to create listeners of slb: %s", job.Result)
+		return newJobError("create listeners of slb", job)
 	}
 	return nil
 }
@@ -281,7 +321,7 @@ func (t *slbClient) updateListeners(ctx context.Context, listeners []*Listener)
 		return fmt.Errorf("update listeners of slb error %w", e)
 	}
 	if resp.Error.Errno != 0 {
-		return fmt.Errorf("update listeners of slb error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return newAPIError("update listeners of slb", resp.Error)
 	}
 
 	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
@@ -289,7 +329,7 @@ func (t *slbClient) updateListeners(ctx context.Context, listeners []*Listener)
 		return e
 	}
 	if !job.Success {
-		return fmt.Errorf("failed to update listeners of slb: %s", job.Result)
+		return newJobError("update listeners of slb", job)
 	}
 	return nil
 }
@@ -311,7 +351,7 @@ func (t *slbClient) deleteListeners(ctx context.Context, listeners []*Listener)
 		return fmt.Errorf("delete listeners of slb error %w", e)
 	}
 	if resp.Error.Errno != 0 {
-		return fmt.Errorf("delete listeners of slb error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return newAPIError("delete listeners of slb", resp.Error)
 	}
 
 	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
@@ -319,7 +359,7 @@ func (t *slbClient) deleteListeners(ctx context.Context, listeners []*Listener)
 		return e
 	}
 	if !job.Success {
-		return fmt.Errorf("failed to delete listeners of slb: %s", job.Result)
+		return newJobError("delete listeners of slb", job)
 	}
 	return nil
 }
@@ -330,40 +370,29 @@ func (t *slbClient) SyncListenerMembers(ctx context.Context, uuid string, dc2Nam
 	}
 
 	klog.V(4).Infof("syncing listener members of slb %s", uuid)
-	dc2Uuids, e := t.getDc2UUIDsByNames(ctx, t.vpcUuid, dc2Names)
+	dc2Uuids, missing, e := t.getDc2UUIDsByNames(ctx, t.vpcUuid, dc2Names)
 	if e != nil {
 		return e
 	}
-
-	req := &compute.ListSLBListenerRequest{
-		Start:     0,
-		Limit:     maxSlbListeners,
-		Condition: &compute.ListSLBListenerRequest_Condition{SlbUuid: uuid},
+	// members of dc2 not resolved are kept, they may be missed by a stale cache
+	keep := make(map[string]bool, len(missing))
+	for _, n := range missing {
+		keep[n] = true
 	}
-	resp, e := t.cli.ListSLBListener(ctx, req)
+
+	listeners, e := t.listListeners(ctx, uuid)
 	if e != nil {
-		return fmt.Errorf("list listeners of slb error %w", e)
-	}
-	if resp.Error.Errno != 0 {
-		return fmt.Errorf("list listeners of slb error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return e
 	}
 
-	for _, l := range resp.Data {
-		req := &compute.ListPoolMembersRequest{
-			Start:     0,
-			Limit:     maxDc2,
-			Condition: &compute.ListPoolMembersRequest_Condition{PoolUuid: l.PoolUuid},
-		}
-		respMem, e := t.cli.ListPoolMembers(ctx, req)
+	for _, l := range listeners {
+		members, e := t.listPoolMembers(ctx, l.PoolUuid)
 		if e != nil {
-			return fmt.Errorf("list pool members of slb listener error %w", e)
-		}
-		if respMem.Error.Errno != 0 {
-			return fmt.Errorf("list pool members of slb listener error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+			return e
 		}
 
-		existMem := make(map[string]*compute.PoolMemberInfo, len(respMem.Data))
-		for _, m := range respMem.Data {
+		existMem := make(map[string]*compute.PoolMemberInfo, len(members))
+		for _, m := range members {
 			existMem[m.Dc2.Dc2Uuid] = m
 		}
 
@@ -377,6 +406,9 @@ func (t *slbClient) SyncListenerMembers(ctx context.Context, uuid string, dc2Nam
 			delete(existMem, n)
 		}
 		for _, m := range existMem {
+			if keep[m.GetDc2().GetName()] {
+				continue
+			}
 			deleteMem = append(deleteMem, m.SlbMemberUuid)
 		}
 
@@ -392,7 +424,47 @@ func (t *slbClient) SyncListenerMembers(ctx context.Context, uuid string, dc2Nam
 			return e
 		}
 	}
-	return nil
+	return t.missingDc2(missing)
+}
+
+func (t *slbClient) listListeners(ctx context.Context, uuid string) ([]*compute.ListSLBListenerResponse_Data, error) {
+	var listeners []*compute.ListSLBListenerResponse_Data
+	e := listPages(maxSlbListeners, func(start, limit int32) (int, error) {
+		resp, e := t.cli.ListSLBListener(ctx, &compute.ListSLBListenerRequest{
+			Start:     start,
+			Limit:     limit,
+			Condition: &compute.ListSLBListenerRequest_Condition{SlbUuid: uuid},
+		})
+		if e != nil {
+			return 0, fmt.Errorf("list listeners of slb error %w", e)
+		}
+		if resp.Error.Errno != 0 {
+			return 0, newAPIError("list listeners of slb", resp.Error)
+		}
+		listeners = append(listeners, resp.Data...)
+		return len(resp.Data), nil
+	})
+	return listeners, e
+}
+
+func (t *slbClient) listPoolMembers(ctx context.Context, poolUuid string) ([]*compute.PoolMemberInfo, error) {
+	var members []*compute.PoolMemberInfo
+	e := listPages(maxSlbMembers, func(start, limit int32) (int, error) {
+		resp, e := t.cli.ListPoolMembers(ctx, &compute.ListPoolMembersRequest{
+			Start:     start,
+			Limit:     limit,
+			Condition: &compute.ListPoolMembersRequest_Condition{PoolUuid: poolUuid},
+		})
+		if e != nil {
+			return 0, fmt.Errorf("list pool members of slb listener error %w", e)
+		}
+		if resp.Error.Errno != 0 {
+			return 0, newAPIError("list pool members of slb listener", resp.Error)
+		}
+		members = append(members, resp.Data...)
+		return len(resp.Data), nil
+	})
+	return members, e
 }
 
 func (t *slbClient) addListenerMembers(ctx context.Context, poolUuid string, dc2Uuid []string, port int64) error {
@@ -416,7 +488,7 @@ func (t *slbClient) addListenerMembers(ctx context.Context, poolUuid string, dc2
 		return fmt.Errorf("add members of slb pool error %w", e)
 	}
 	if resp.Error.Errno != 0 {
-		return fmt.Errorf("add members of slb pool error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return newAPIError("add members of slb pool", resp.Error)
 	}
 
 	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
@@ -424,7 +496,7 @@ func (t *slbClient) addListenerMembers(ctx context.Context, poolUuid string, dc2
 		return e
 	}
 	if !job.Success {
-		return fmt.Errorf("failed to add members of slb pool: %s", job.Result)
+		return newJobError("add members of slb pool", job)
 	}
 	return nil
 }
@@ -446,7 +518,7 @@ func (t *slbClient) deleteListenerMembers(ctx context.Context, memUuid []string)
 		return fmt.Errorf("delete members of slb pool error %w", e)
 	}
 	if resp.Error.Errno != 0 {
-		return fmt.Errorf("delete members of slb pool error %s (%d)", resp.Error.Errmsg, resp.Error.Errno)
+		return newAPIError("delete members of slb pool", resp.Error)
 	}
 
 	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
@@ -454,7 +526,7 @@ func (t *slbClient) deleteListenerMembers(ctx context.Context, memUuid []string)
 		return e
 	}
 	if !job.Success {
-		return fmt.Errorf("failed to delete members of slb pool: %s", job.Result)
+		return newJobError("delete members of slb pool", job)
 	}
 	return nil
 }
@@ -1,30 +1,41 @@
 package pkg
 
 import (
-	"fmt"
 	"context"
+	"fmt"
 
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
 	"github.com/pborman/uuid"
 )
 
 type slbInfo struct {
 	name string
-	eip string
+	eip  string
 }
 
 type mockSlbClient struct {
-	slb map[string]*slbInfo
-	count int
+	slb     map[string]*slbInfo
+	count   int
+	vpcUuid string
+	client  *mockClient
 }
 
+var _ SlbClient = (*mockSlbClient)(nil)
+
 func (t *mockSlbClient) Create(ctx context.Context, regionID, zoneID, name string, bandwidth int64) (string, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return "", e
+	}
 	id := uuid.NewUUID().String()
 	t.count++
 	t.slb[id] = &slbInfo{name: name, eip: fmt.Sprintf("192.168.0.%d", t.count)}
 	return id, nil
 }
 
-func (t *mockSlbClient)	GetExternalIP(ctx context.Context, uuid string) (string, error) {
+func (t *mockSlbClient) GetExternalIP(ctx context.Context, uuid string) (string, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return "", e
+	}
 	s, ok := t.slb[uuid]
 	if !ok {
 		return "", fmt.Errorf("slb %s %w", uuid, NotFound)
@@ -32,7 +43,10 @@ func (t *mockSlbClient)	GetExternalIP(ctx context.Context, uuid string) (string,
 	return s.eip, nil
 }
 
-func (t *mockSlbClient)	Delete(ctx context.Context, uuid string) error {
+func (t *mockSlbClient) Delete(ctx context.Context, uuid string) error {
+	if e := t.client.checkClosed(); e != nil {
+		return e
+	}
 	_, ok := t.slb[uuid]
 	if !ok {
 		return fmt.Errorf("slb %s %w", uuid, NotFound)
@@ -41,18 +55,57 @@ func (t *mockSlbClient)	Delete(ctx context.Context, uuid string) error {
 	return nil
 }
 
-func (t *mockSlbClient)	SyncListeners(ctx context.Context, uuid string, listeners []*Listener, dc2Names []string) error {
+func (t *mockSlbClient) SyncListeners(ctx context.Context, uuid string, listeners []*Listener, dc2Names []string) error {
+	if e := t.client.checkClosed(); e != nil {
+		return e
+	}
 	_, ok := t.slb[uuid]
 	if !ok {
 		return fmt.Errorf("slb %s %w", uuid, NotFound)
 	}
-	return nil
+	return t.missingDc2(dc2Names)
 }
 
-func (t *mockSlbClient)	SyncListenerMembers(ctx context.Context, uuid string, dc2Names []string) error {
+func (t *mockSlbClient) SyncListenerMembers(ctx context.Context, uuid string, dc2Names []string) error {
+	if e := t.client.checkClosed(); e != nil {
+		return e
+	}
 	_, ok := t.slb[uuid]
 	if !ok {
 		return fmt.Errorf("slb %s %w", uuid, NotFound)
 	}
-	return nil
-}
\ No newline at end of file
+	return t.missingDc2(dc2Names)
+}
+
+func (t *mockSlbClient) WaitFor(ctx context.Context, uuid string, cond SlbPredicate) (*compute.SlbInfo, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	s, ok := t.slb[uuid]
+	if !ok {
+		return nil, fmt.Errorf("slb %s %w", uuid, NotFound)
+	}
+	info := &compute.SlbInfo{SlbUuid: uuid, Name: s.name, Beip: &compute.BeipInfo{Ip: s.eip}}
+	done, e := cond(info)
+	if e != nil || done {
+		return info, e
+	}
+	// nothing changes a mock slb in the background
+	<-ctx.Done()
+	return info, fmt.Errorf("wait for slb %s error %w", uuid, ctx.Err())
+}
+
+// missingDc2 returns a Dc2NotFoundError of names not created by the mock dc2 client, like the real client does once
+// others are synced
+func (t *mockSlbClient) missingDc2(dc2Names []string) error {
+	var missing []string
+	for _, n := range dc2Names {
+		if _, e := t.client.dc2.find(Dc2Ref{Name: n}); e != nil {
+			missing = append(missing, n)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &Dc2NotFoundError{VpcUuid: t.vpcUuid, Names: missing}
+}
@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"google.golang.org/grpc"
+)
+
+// fakeSlbClient lists one listener with the members, and records added and deleted ones with jobs done at once
+type fakeSlbClient struct {
+	compute.SLBClient
+	members []*compute.PoolMemberInfo
+	added   []string // dc2 uuids
+	deleted []string // member uuids
+}
+
+func (t *fakeSlbClient) ListSLBListener(ctx context.Context, req *compute.ListSLBListenerRequest, opts ...grpc.CallOption) (*compute.ListSLBListenerResponse, error) {
+	return &compute.ListSLBListenerResponse{
+		Error: &base.Error{},
+		Data:  []*compute.ListSLBListenerResponse_Data{{SlbListenerUuid: "lis-1", PoolUuid: "pool-1", MemberPorts: []int64{8080}}},
+	}, nil
+}
+
+func (t *fakeSlbClient) ListPoolMembers(ctx context.Context, req *compute.ListPoolMembersRequest, opts ...grpc.CallOption) (*compute.ListPoolMembersResponse, error) {
+	return &compute.ListPoolMembersResponse{Error: &base.Error{}, Data: t.members}, nil
+}
+
+func (t *fakeSlbClient) AddSLBMemberToPool(ctx context.Context, req *compute.AddSLBMemberToPoolRequest, opts ...grpc.CallOption) (*compute.AddSLBMemberToPoolResponse, error) {
+	for _, m := range req.Members {
+		t.added = append(t.added, m.Dc2Uuid)
+	}
+	return &compute.AddSLBMemberToPoolResponse{Error: &base.Error{}, Data: []*base.JobInfo{{Done: true, Success: true}}}, nil
+}
+
+func (t *fakeSlbClient) DeleteSLBMember(ctx context.Context, req *compute.DeleteSLBMemberRequest, opts ...grpc.CallOption) (*compute.DeleteSLBMemberResponse, error) {
+	for _, m := range req.Members {
+		t.deleted = append(t.deleted, m.SlbMemberUuid)
+	}
+	return &compute.DeleteSLBMemberResponse{Error: &base.Error{}, Data: []*base.JobInfo{{Done: true, Success: true}}}, nil
+}
+
+func TestSyncListenerMembersMissing(t *testing.T) {
+	dc2s := fakeDc2s(4)
+	member := func(uuid string, dc2 *compute.Dc2Info) *compute.PoolMemberInfo {
+		return &compute.PoolMemberInfo{SlbMemberUuid: uuid, Dc2: dc2}
+	}
+	fake := &fakeSlbClient{members: []*compute.PoolMemberInfo{
+		member("mem-0", dc2s[0]), // kept
+		member("mem-1", dc2s[1]), // removed
+		member("mem-9", &compute.Dc2Info{Dc2Uuid: "uuid-9", Name: "node-9"}), // not found, kept
+	}}
+	// node-2 is added, node-9 is not in the vpc any more
+	slb := &slbClient{cli: fake, vpcUuid: "vpc-1", helper: &client{dc2: &fakeDc2Client{dc2s: dc2s[:3]}}}
+
+	e := slb.SyncListenerMembers(context.Background(), "slb-1", []string{"node-0", "node-2", "node-9"})
+	var missing *Dc2NotFoundError
+	if !errors.As(e, &missing) || !errors.Is(e, NotFound) || !reflect.DeepEqual(missing.Names, []string{"node-9"}) {
+		t.Errorf("expect node-9 not found, got %v", e)
+	}
+	sort.Strings(fake.deleted)
+	if !reflect.DeepEqual(fake.added, []string{"uuid-2"}) || !reflect.DeepEqual(fake.deleted, []string{"mem-1"}) {
+		t.Errorf("expect uuid-2 added and mem-1 deleted, got %v and %v", fake.added, fake.deleted)
+	}
+}
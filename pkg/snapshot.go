@@ -0,0 +1,93 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"k8s.io/klog"
+)
+
+const (
+	maxSnapshot = 100
+)
+
+func (t *ebsClient) CreateSnapshot(ctx context.Context, ebsUUID, name string) (string, error) {
+	klog.V(4).Infof("creating snapshot %s of ebs %s", name, ebsUUID)
+	req := &compute.CreateSnapshotRequest{
+		EbsUuid:  ebsUUID,
+		SnapName: name,
+	}
+	resp, e := t.snap.CreateSnapshot(ctx, req)
+	if e != nil {
+		return "", fmt.Errorf("create snapshot error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return "", newAPIError("create snapshot", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return "", e
+	}
+	if !job.Success { // uuid is returned if created anyway, so that it could be cleaned up
+		return job.ResourceUuid, newJobError("create snapshot", job)
+	}
+	return job.ResourceUuid, nil
+}
+
+func (t *ebsClient) ListSnapshots(ctx context.Context, ebsUUID string) ([]*compute.SnapInfo, error) {
+	if ebsUUID == "" { // if uuid is empty, all snapshots will be listed which is not expected
+		return nil, errors.New("empty ebs uuid")
+	}
+	klog.V(4).Infof("listing snapshots of ebs %s", ebsUUID)
+	var snaps []*compute.SnapInfo
+	e := listPages(maxSnapshot, func(start, limit int32) (int, error) {
+		resp, e := t.snap.ListSnapshot(ctx, &compute.ListSnapshotRequest{
+			Start:     start,
+			Limit:     limit,
+			Condition: &compute.ListSnapshotCondition{EbsUuid: ebsUUID},
+		})
+		if e != nil {
+			return 0, fmt.Errorf("list snapshot error %w", e)
+		}
+		if resp.Error.Errno != 0 {
+			return 0, newAPIError("list snapshot", resp.Error)
+		}
+		snaps = append(snaps, resp.Data...)
+		return len(resp.Data), nil
+	})
+	if e != nil {
+		return nil, e
+	}
+	return snaps, nil
+}
+
+func (t *ebsClient) DeleteSnapshot(ctx context.Context, snapUUID string) error {
+	klog.V(4).Infof("deleting snapshot %s", snapUUID)
+	req := &compute.DeleteSnapshotRequest{
+		Snap: []*compute.DeleteSnapshotRequest_Input{{SnapUuid: snapUUID}},
+	}
+	resp, e := t.snap.DeleteSnapshot(ctx, req)
+	if e != nil {
+		return fmt.Errorf("delete snapshot error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("delete snapshot", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success { // not found etc.
+		return newJobError("delete snapshot", job)
+	}
+	return nil
+}
+
+// CreateFromSnapshot creates an ebs with data of the snapshot, which must be in the same region and zone
+func (t *ebsClient) CreateFromSnapshot(ctx context.Context, regionID, zoneID, name, snapUUID string, typ EbsType, sizeGB int64) (string, error) {
+	return t.CreateWithOptions(ctx, regionID, zoneID, &CreateEbsOptions{Name: name, Type: typ, SizeGB: sizeGB, SnapUuid: snapUUID})
+}
@@ -0,0 +1,77 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"google.golang.org/grpc"
+)
+
+// fakeSnapClient deletes snaps with jobs done at once, and fails creating with a uuid
+type fakeSnapClient struct {
+	compute.SnapClient
+	snaps map[string]bool
+}
+
+func (t *fakeSnapClient) CreateSnapshot(ctx context.Context, req *compute.CreateSnapshotRequest, opts ...grpc.CallOption) (*compute.CreateSnapshotResponse, error) {
+	return &compute.CreateSnapshotResponse{
+		Error: &base.Error{},
+		Data:  []*base.JobInfo{{Done: true, ResourceUuid: "snap-failed", Result: "创建快照失败"}},
+	}, nil
+}
+
+func (t *fakeSnapClient) DeleteSnapshot(ctx context.Context, req *compute.DeleteSnapshotRequest, opts ...grpc.CallOption) (*compute.DeleteSnapshotResponse, error) {
+	resp := &compute.DeleteSnapshotResponse{Error: &base.Error{}}
+	for _, in := range req.Snap {
+		job := &base.JobInfo{ResourceUuid: in.SnapUuid, Done: true, Result: snapNotFoundMsg}
+		if t.snaps[in.SnapUuid] {
+			delete(t.snaps, in.SnapUuid)
+			job.Success, job.Result = true, ""
+		}
+		resp.Data = append(resp.Data, job)
+	}
+	return resp, nil
+}
+
+func newFakeSnapClient(fake *fakeSnapClient, ebs *fakeEbsClient) *ebsClient {
+	c := newFakeEbsClient(ebs)
+	c.snap = fake
+	return c
+}
+
+func TestSnapshotNotFound(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeSnapClient(&fakeSnapClient{snaps: map[string]bool{"snap-1": true}}, &fakeEbsClient{snaps: map[string]bool{"snap-1": true}})
+
+	if e := c.DeleteSnapshot(ctx, "snap-1"); e != nil {
+		t.Errorf("expect snap-1 deleted, got %v", e)
+	}
+	if e := c.DeleteSnapshot(ctx, "snap-1"); !errors.Is(e, NotFound) {
+		t.Errorf("expect NotFound, got %v", e)
+	}
+
+	if _, e := c.CreateFromSnapshot(ctx, "gz", "gz02", "data", "snap-1", EbsTypeSSD, 20); e != nil {
+		t.Errorf("expect created from snap-1, got %v", e)
+	}
+	if _, e := c.CreateFromSnapshot(ctx, "gz", "gz02", "data", "snap-2", EbsTypeSSD, 20); !errors.Is(e, NotFound) {
+		t.Errorf("expect NotFound, got %v", e)
+	}
+}
+
+func TestCreateSnapshotFailed(t *testing.T) {
+	c := newFakeSnapClient(&fakeSnapClient{}, &fakeEbsClient{})
+	id, e := c.CreateSnapshot(context.Background(), "ebs-1", "snap")
+	if id != "snap-failed" || e == nil {
+		t.Errorf("expect snap-failed with an error, got %s, error %v", id, e)
+	}
+}
+
+func TestListSnapshotsEmptyEbs(t *testing.T) {
+	// the embedded nil SnapClient panics if listed
+	if _, e := newFakeSnapClient(&fakeSnapClient{}, &fakeEbsClient{}).ListSnapshots(context.Background(), ""); e == nil {
+		t.Error("expect an error of empty ebs uuid")
+	}
+}
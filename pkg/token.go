@@ -0,0 +1,90 @@
+package pkg
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"k8s.io/klog"
+)
+
+// FileTokenSource reads the api token from a file, and reloads it once the file is modified,
+// eg. a kubernetes secret mounted as a volume
+func FileTokenSource(path string) oauth2.TokenSource {
+	return &fileTokenSource{path: path}
+}
+
+type fileTokenSource struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	token   *oauth2.Token
+}
+
+func (t *fileTokenSource) Token() (*oauth2.Token, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	token, e := t.load()
+	if e != nil {
+		if t.token != nil { // keep using the last token, the file may be in the middle of an update
+			klog.Warningf("load token file %s error %v, use the cached token", t.path, e)
+			return t.token, nil
+		}
+		return nil, e
+	}
+	return token, nil
+}
+
+// load reads the token if the file is modified since last loaded
+func (t *fileTokenSource) load() (*oauth2.Token, error) {
+	fi, e := os.Stat(t.path)
+	if e != nil {
+		return nil, fmt.Errorf("stat token file error %w", e)
+	}
+	if t.token != nil && fi.ModTime().Equal(t.modTime) && fi.Size() == t.size {
+		return t.token, nil
+	}
+
+	klog.V(4).Infof("loading token from %s", t.path)
+	data, e := ioutil.ReadFile(t.path)
+	if e != nil {
+		return nil, fmt.Errorf("read token file error %w", e)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return nil, fmt.Errorf("empty token in file %s", t.path)
+	}
+	t.token = bearerToken(token)
+	t.modTime = fi.ModTime()
+	t.size = fi.Size()
+	return t.token, nil
+}
+
+// EnvTokenSource reads the api token from an environment variable on every request
+func EnvTokenSource(name string) oauth2.TokenSource {
+	return envTokenSource(name)
+}
+
+type envTokenSource string
+
+func (t envTokenSource) Token() (*oauth2.Token, error) {
+	token := strings.TrimSpace(os.Getenv(string(t)))
+	if token == "" {
+		return nil, fmt.Errorf("empty token in env %s", string(t))
+	}
+	return bearerToken(token), nil
+}
+
+func bearerToken(token string) *oauth2.Token {
+	return &oauth2.Token{
+		AccessToken: token,
+		TokenType:   "bearer",
+	}
+}
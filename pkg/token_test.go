@@ -0,0 +1,54 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileTokenSource(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "token")
+	ts := FileTokenSource(path)
+
+	if _, e := ts.Token(); e == nil {
+		t.Error("expect an error without the file")
+	}
+
+	write := func(token string) {
+		if e := ioutil.WriteFile(path, []byte(token), 0600); e != nil {
+			t.Fatal(e)
+		}
+	}
+	expect := func(token string) {
+		t.Helper()
+		got, e := ts.Token()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if got.AccessToken != token {
+			t.Errorf("expect token %q, got %q", token, got.AccessToken)
+		}
+	}
+
+	write("first\n")
+	expect("first")
+
+	write("") // truncated by a non-atomic rewrite
+	expect("first")
+
+	write("second-token")
+	expect("second-token")
+
+	if e := os.Remove(path); e != nil {
+		t.Fatal(e)
+	}
+	expect("second-token")
+
+	if e := os.Mkdir(path, 0700); e != nil { // stat ok, but read fails
+		t.Fatal(e)
+	}
+	expect("second-token")
+}
@@ -0,0 +1,259 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"k8s.io/klog"
+)
+
+const (
+	maxVpc    = 100
+	maxSubnet = 100
+)
+
+type VpcClient interface {
+	Create(ctx context.Context, regionID, name, cidr string, subnets []*Subnet) (string, error)
+	Get(ctx context.Context, vpcUUID string) (*compute.VpcInfo, error)
+	GetByName(ctx context.Context, regionID, name string) (*compute.VpcInfo, error)
+	List(ctx context.Context, regionID string) ([]*compute.VpcInfo, error)
+	Delete(ctx context.Context, vpcUUID string) error
+
+	CreateSubnet(ctx context.Context, regionID, vpcUUID string, subnet *Subnet) (string, error)
+	GetSubnet(ctx context.Context, vpcUUID, subnetUUID string) (*compute.SubnetInfo, error)
+	ListSubnets(ctx context.Context, regionID, vpcUUID string) ([]*compute.SubnetInfo, error)
+	DeleteSubnet(ctx context.Context, vpcUUID, subnetUUID string) error
+}
+
+// Subnet is a subnet to create in a zone of the vpc, Cidr must be within the cidr of the vpc
+type Subnet struct {
+	Name   string
+	Cidr   string
+	ZoneID string
+}
+
+type vpcClient struct {
+	cli compute.VpcClient
+	helper
+}
+
+var _ VpcClient = (*vpcClient)(nil)
+
+func (t *vpcClient) Create(ctx context.Context, regionID, name, cidr string, subnets []*Subnet) (string, error) {
+	klog.V(4).Infof("creating vpc %s, cidr %s", name, cidr)
+	req := &compute.CreateVpcRequest{
+		Header: &base.Header{RegionId: regionID},
+		Name:   name,
+		Cidr:   cidr,
+	}
+	for _, s := range subnets {
+		req.Subnet = append(req.Subnet, &compute.CreateSubnetInput{Name: s.Name, Cidr: s.Cidr, ZoneId: s.ZoneID})
+	}
+	resp, e := t.cli.CreateVpc(ctx, req)
+	if e != nil {
+		return "", fmt.Errorf("create vpc error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return "", newAPIError("create vpc", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], regionID, "")
+	if e != nil {
+		return "", e
+	}
+	if !job.Success { // uuid is returned if created anyway, so that it could be cleaned up
+		return job.ResourceUuid, newJobError("create vpc", job)
+	}
+	return job.ResourceUuid, nil
+}
+
+func (t *vpcClient) Get(ctx context.Context, vpcUUID string) (*compute.VpcInfo, error) {
+	klog.V(4).Infof("get vpc %s", vpcUUID)
+	resp, e := t.cli.GetVpcByUuid(ctx, &compute.GetVpcByUuidRequest{
+		VpcUuid: vpcUUID,
+	})
+	if e != nil {
+		return nil, fmt.Errorf("get vpc by uuid: %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return nil, newAPIError("get vpc by uuid", resp.Error)
+	}
+
+	infos := resp.GetData()
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("vpc %s %w", vpcUUID, NotFound)
+	}
+	if len(infos) > 1 {
+		return nil, fmt.Errorf("get vpc by uuid, got too much: %v", infos)
+	}
+	return infos[0], nil
+}
+
+// GetByName finds the only vpc with the exact name, a Conflict error is returned if the name is ambiguous
+func (t *vpcClient) GetByName(ctx context.Context, regionID, name string) (*compute.VpcInfo, error) {
+	klog.V(4).Infof("get vpc by name %s", name)
+	vpcs, e := t.List(ctx, regionID)
+	if e != nil {
+		return nil, e
+	}
+
+	var found []*compute.VpcInfo
+	for _, v := range vpcs {
+		if v.GetName() == name {
+			found = append(found, v)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("vpc %s %w", name, NotFound)
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("%d vpc named %s: %w", len(found), name, Conflict)
+	}
+	return found[0], nil
+}
+
+func (t *vpcClient) List(ctx context.Context, regionID string) ([]*compute.VpcInfo, error) {
+	klog.V(4).Infof("listing vpc in region %s", regionID)
+	var vpcs []*compute.VpcInfo
+	e := listPages(maxVpc, func(start, limit int32) (int, error) {
+		resp, e := t.cli.ListVpc(ctx, &compute.ListVpcRequest{
+			Header: &base.Header{RegionId: regionID},
+			Start:  start,
+			Limit:  limit,
+		})
+		if e != nil {
+			return 0, fmt.Errorf("list vpc error %w", e)
+		}
+		if resp.Error.Errno != 0 {
+			return 0, newAPIError("list vpc", resp.Error)
+		}
+		vpcs = append(vpcs, resp.Data...)
+		return len(resp.Data), nil
+	})
+	if e != nil {
+		return nil, e
+	}
+	return vpcs, nil
+}
+
+func (t *vpcClient) Delete(ctx context.Context, vpcUUID string) error {
+	klog.V(4).Infof("deleting vpc %s", vpcUUID)
+	req := &compute.DeleteVpcRequest{
+		Vpc: []*compute.DeleteVpcRequest_Input{{VpcUuid: vpcUUID}},
+	}
+	resp, e := t.cli.DeleteVpc(ctx, req)
+	if e != nil {
+		return fmt.Errorf("delete vpc error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("delete vpc", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success { // not found etc.
+		return newJobError("delete vpc", job)
+	}
+	return nil
+}
+
+func (t *vpcClient) CreateSubnet(ctx context.Context, regionID, vpcUUID string, subnet *Subnet) (string, error) {
+	klog.V(4).Infof("creating subnet %s of vpc %s, cidr %s", subnet.Name, vpcUUID, subnet.Cidr)
+	req := &compute.CreateSubnetRequest{
+		Header:  &base.Header{RegionId: regionID, ZoneId: subnet.ZoneID},
+		VpcUuid: vpcUUID,
+		Subnet:  []*compute.CreateSubnetInput{{Name: subnet.Name, Cidr: subnet.Cidr, ZoneId: subnet.ZoneID}},
+	}
+	resp, e := t.cli.CreateSubnet(ctx, req)
+	if e != nil {
+		return "", fmt.Errorf("create subnet error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return "", newAPIError("create subnet", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], regionID, subnet.ZoneID)
+	if e != nil {
+		return "", e
+	}
+	if !job.Success { // uuid is returned if created anyway, so that it could be cleaned up
+		return job.ResourceUuid, newJobError("create subnet", job)
+	}
+	return job.ResourceUuid, nil
+}
+
+func (t *vpcClient) GetSubnet(ctx context.Context, vpcUUID, subnetUUID string) (*compute.SubnetInfo, error) {
+	klog.V(4).Infof("get subnet %s of vpc %s", subnetUUID, vpcUUID)
+	resp, e := t.cli.GetSubnetByUuid(ctx, &compute.GetSubnetByUuidRequest{
+		VpcUuid:    vpcUUID,
+		SubnetUuid: subnetUUID,
+	})
+	if e != nil {
+		return nil, fmt.Errorf("get subnet by uuid: %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return nil, newAPIError("get subnet by uuid", resp.Error)
+	}
+
+	infos := resp.GetData()
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("subnet %s %w", subnetUUID, NotFound)
+	}
+	if len(infos) > 1 {
+		return nil, fmt.Errorf("get subnet by uuid, got too much: %v", infos)
+	}
+	return infos[0], nil
+}
+
+func (t *vpcClient) ListSubnets(ctx context.Context, regionID, vpcUUID string) ([]*compute.SubnetInfo, error) {
+	klog.V(4).Infof("listing subnets of vpc %s", vpcUUID)
+	var subnets []*compute.SubnetInfo
+	e := listPages(maxSubnet, func(start, limit int32) (int, error) {
+		resp, e := t.cli.ListSubnet(ctx, &compute.ListSubnetRequest{
+			Header:    &base.Header{RegionId: regionID},
+			Start:     start,
+			Limit:     limit,
+			Condition: &compute.ListSubnetCondition{VpcUuid: vpcUUID},
+		})
+		if e != nil {
+			return 0, fmt.Errorf("list subnet error %w", e)
+		}
+		if resp.Error.Errno != 0 {
+			return 0, newAPIError("list subnet", resp.Error)
+		}
+		subnets = append(subnets, resp.Data...)
+		return len(resp.Data), nil
+	})
+	if e != nil {
+		return nil, e
+	}
+	return subnets, nil
+}
+
+func (t *vpcClient) DeleteSubnet(ctx context.Context, vpcUUID, subnetUUID string) error {
+	klog.V(4).Infof("deleting subnet %s of vpc %s", subnetUUID, vpcUUID)
+	req := &compute.DeleteSubnetRequest{
+		VpcUuid: vpcUUID,
+		Subnet:  []*compute.DeleteSubnetRequest_Input{{SubnetUuid: subnetUUID}},
+	}
+	resp, e := t.cli.DeleteSubnet(ctx, req)
+	if e != nil {
+		return fmt.Errorf("delete subnet error %w", e)
+	}
+	if resp.Error.Errno != 0 {
+		return newAPIError("delete subnet", resp.Error)
+	}
+
+	job, e := t.waitForJob(ctx, resp.Data[0], "", "")
+	if e != nil {
+		return e
+	}
+	if !job.Success { // not found etc.
+		return newJobError("delete subnet", job)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/didiyun/didiyun-go-sdk/base/v1"
+	"github.com/didiyun/didiyun-go-sdk/compute/v1"
+	"github.com/pborman/uuid"
+)
+
+type vpcInfo struct {
+	vpc     *compute.VpcInfo
+	subnets map[string]*compute.SubnetInfo
+}
+
+type mockVpcClient struct {
+	vpc    map[string]*vpcInfo
+	client *mockClient
+}
+
+var _ VpcClient = (*mockVpcClient)(nil)
+
+func (t *mockVpcClient) Create(ctx context.Context, regionID, name, cidr string, subnets []*Subnet) (string, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return "", e
+	}
+	id := uuid.NewUUID().String()
+	t.vpc[id] = &vpcInfo{
+		vpc:     &compute.VpcInfo{VpcUuid: id, Name: name, Cidr: cidr},
+		subnets: make(map[string]*compute.SubnetInfo),
+	}
+	for _, s := range subnets {
+		if _, e := t.CreateSubnet(ctx, regionID, id, s); e != nil {
+			return "", e
+		}
+	}
+	return id, nil
+}
+
+func (t *mockVpcClient) Get(ctx context.Context, vpcUUID string) (*compute.VpcInfo, error) {
+	v, e := t.get(vpcUUID)
+	if e != nil {
+		return nil, e
+	}
+	return v.vpc, nil
+}
+
+func (t *mockVpcClient) GetByName(ctx context.Context, regionID, name string) (*compute.VpcInfo, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	var found []*compute.VpcInfo
+	for _, v := range t.vpc {
+		if v.vpc.Name == name {
+			found = append(found, v.vpc)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("vpc %s %w", name, NotFound)
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("%d vpc named %s: %w", len(found), name, Conflict)
+	}
+	return found[0], nil
+}
+
+func (t *mockVpcClient) List(ctx context.Context, regionID string) ([]*compute.VpcInfo, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	var vpcs []*compute.VpcInfo
+	for _, v := range t.vpc {
+		vpcs = append(vpcs, v.vpc)
+	}
+	return vpcs, nil
+}
+
+func (t *mockVpcClient) Delete(ctx context.Context, vpcUUID string) error {
+	if _, e := t.get(vpcUUID); e != nil {
+		return e
+	}
+	delete(t.vpc, vpcUUID)
+	return nil
+}
+
+func (t *mockVpcClient) CreateSubnet(ctx context.Context, regionID, vpcUUID string, subnet *Subnet) (string, error) {
+	v, e := t.get(vpcUUID)
+	if e != nil {
+		return "", e
+	}
+	id := uuid.NewUUID().String()
+	v.subnets[id] = &compute.SubnetInfo{
+		SubnetUuid: id,
+		Name:       subnet.Name,
+		Cidr:       subnet.Cidr,
+		Zone:       &base.ZoneInfo{Id: subnet.ZoneID},
+	}
+	return id, nil
+}
+
+func (t *mockVpcClient) GetSubnet(ctx context.Context, vpcUUID, subnetUUID string) (*compute.SubnetInfo, error) {
+	v, e := t.get(vpcUUID)
+	if e != nil {
+		return nil, e
+	}
+	s, ok := v.subnets[subnetUUID]
+	if !ok {
+		return nil, fmt.Errorf("subnet %s %w", subnetUUID, NotFound)
+	}
+	return s, nil
+}
+
+func (t *mockVpcClient) ListSubnets(ctx context.Context, regionID, vpcUUID string) ([]*compute.SubnetInfo, error) {
+	v, e := t.get(vpcUUID)
+	if e != nil {
+		return nil, e
+	}
+	var subnets []*compute.SubnetInfo
+	for _, s := range v.subnets {
+		subnets = append(subnets, s)
+	}
+	return subnets, nil
+}
+
+func (t *mockVpcClient) DeleteSubnet(ctx context.Context, vpcUUID, subnetUUID string) error {
+	if _, e := t.GetSubnet(ctx, vpcUUID, subnetUUID); e != nil {
+		return e
+	}
+	delete(t.vpc[vpcUUID].subnets, subnetUUID)
+	return nil
+}
+
+func (t *mockVpcClient) get(vpcUUID string) (*vpcInfo, error) {
+	if e := t.client.checkClosed(); e != nil {
+		return nil, e
+	}
+	v, ok := t.vpc[vpcUUID]
+	if !ok {
+		return nil, fmt.Errorf("vpc %s %w", vpcUUID, NotFound)
+	}
+	return v, nil
+}